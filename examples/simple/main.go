@@ -5,17 +5,28 @@ import (
 	"net/http"
 )
 
+type userPath struct {
+	ID int `req:"id"`
+}
+
 func home() string {
 	return "Hello!"
 }
 
+func getUser(p *userPath) map[string]int {
+	return map[string]int{"id": p.ID}
+}
+
 func main() {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", kit.F(home))
+	r := kit.NewRouter()
+	r.Get("/{$}", home)
+
+	api := r.Group("/api")
+	api.Get("/users/{id}", getUser)
 
 	server := http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: r,
 	}
 	server.ListenAndServe()
 }
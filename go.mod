@@ -1,5 +1,5 @@
 
 module github.com/smallfz/httpkit
 
-go 1.14
+go 1.23
 
@@ -0,0 +1,122 @@
+package kit
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+)
+
+// AccessLogField is a field of the records written by AccessLog.
+type AccessLogField string
+
+const (
+	LogMethod    AccessLogField = "method"
+	LogPath      AccessLogField = "path"
+	LogQuery     AccessLogField = "query"
+	LogPattern   AccessLogField = "pattern" // the ServeMux pattern which matched the request.
+	LogStatus    AccessLogField = "status"
+	LogBytes     AccessLogField = "bytes"
+	LogTTFB      AccessLogField = "ttfb"
+	LogDuration  AccessLogField = "duration"
+	LogRemote    AccessLogField = "remote"
+	LogUserAgent AccessLogField = "user_agent"
+	LogError     AccessLogField = "error" // with the phase, if an error was answered.
+	LogHijacked  AccessLogField = "hijacked"
+)
+
+// DefaultAccessLogFields are the fields logged when AccessLogOptions
+// doesn't name any.
+var DefaultAccessLogFields = []AccessLogField{
+	LogMethod, LogPath, LogStatus, LogBytes, LogDuration, LogError,
+}
+
+// AccessLogOptions configures AccessLog.
+type AccessLogOptions struct {
+	Logger *slog.Logger     // slog.Default() if nil.
+	Level  slog.Level       // of successful requests. Those answered 5xx are logged at slog.LevelError.
+	Fields []AccessLogField // DefaultAccessLogFields if empty.
+
+	// Sample tells whether a request is logged. Every request is logged
+	// if it is nil. See SampleRate.
+	Sample func(c *Call) bool
+}
+
+// SampleRate returns a sampler keeping the given fraction of requests,
+// and every request answered 5xx.
+func SampleRate(rate float64) func(c *Call) bool {
+	return func(c *Call) bool {
+		return c.Stats.Status >= 500 || rand.Float64() < rate
+	}
+}
+
+// AccessLog returns an interceptor logging a record for every response of
+// bound funcs, e.g. kit.Use(kit.AccessLog(kit.AccessLogOptions{})).
+func AccessLog(opts AccessLogOptions) Interceptor {
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = DefaultAccessLogFields
+	}
+	return Interceptor{
+		AfterResponse: func(c *Call) {
+			if opts.Sample != nil && !opts.Sample(c) {
+				return
+			}
+			logger := opts.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			level := opts.Level
+			if c.Stats.Status >= 500 {
+				level = slog.LevelError
+			}
+			ctx := c.Request.Context()
+			if !logger.Enabled(ctx, level) {
+				return
+			}
+			attrs := make([]slog.Attr, 0, len(fields)+1)
+			for _, f := range fields {
+				attrs = appendAccessAttr(attrs, f, c)
+			}
+			logger.LogAttrs(context.WithoutCancel(ctx), level, "access", attrs...)
+		},
+	}
+}
+
+func appendAccessAttr(attrs []slog.Attr, f AccessLogField, c *Call) []slog.Attr {
+	req := c.Request
+	key := string(f)
+	switch f {
+	case LogMethod:
+		return append(attrs, slog.String(key, req.Method))
+	case LogPath:
+		return append(attrs, slog.String(key, req.URL.Path))
+	case LogQuery:
+		return append(attrs, slog.String(key, req.URL.RawQuery))
+	case LogPattern:
+		return append(attrs, slog.String(key, req.Pattern))
+	case LogStatus:
+		status := c.Stats.Status
+		if status == 0 && !c.Stats.Hijacked {
+			status = http.StatusOK
+		}
+		return append(attrs, slog.Int(key, status))
+	case LogBytes:
+		return append(attrs, slog.Int64(key, c.Stats.Bytes))
+	case LogTTFB:
+		return append(attrs, slog.Duration(key, c.Stats.TTFB))
+	case LogDuration:
+		return append(attrs, slog.Duration(key, c.Stats.Duration))
+	case LogRemote:
+		return append(attrs, slog.String(key, req.RemoteAddr))
+	case LogUserAgent:
+		return append(attrs, slog.String(key, req.UserAgent()))
+	case LogError:
+		if c.Err != nil {
+			return append(attrs, slog.String(key, c.Err.Error()), slog.String("phase", string(c.Phase)))
+		}
+	case LogHijacked:
+		return append(attrs, slog.Bool(key, c.Stats.Hijacked))
+	}
+	return attrs
+}
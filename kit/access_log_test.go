@@ -0,0 +1,118 @@
+package kit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseStats(t *testing.T) {
+	var stats ResponseStats
+	ic := Interceptor{AfterResponse: func(c *Call) { stats = c.Stats }}
+
+	cases := []struct {
+		fn     interface{}
+		status int
+		bytes  int64
+	}{
+		{func() string { return "hello" }, 200, 5},
+		{func() (int, string) { return 201, "" }, 201, 0},
+		{func() error { return NewError(404, "nope") }, 404, 4},
+		{func(w http.ResponseWriter) {
+			time.Sleep(5 * time.Millisecond)
+			w.Write([]byte("ab"))
+			w.Write([]byte("c"))
+		}, 200, 3},
+	}
+	for i, c := range cases {
+		stats = ResponseStats{}
+		F(c.fn, WithInterceptors(ic))(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		if stats.Status != c.status || stats.Bytes != c.bytes {
+			t.Fatalf("case %d: expects %d %d. got %+v", i, c.status, c.bytes, stats)
+			return
+		}
+		if stats.TTFB <= 0 || stats.Duration < stats.TTFB {
+			t.Fatalf("case %d: timing is not expected: %+v", i, stats)
+			return
+		}
+	}
+	if stats.TTFB < 5*time.Millisecond {
+		t.Fatalf("ttfb is not expected: %v", stats.TTFB)
+		return
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	opts := AccessLogOptions{
+		Logger: logger,
+		Fields: []AccessLogField{LogMethod, LogPath, LogPattern, LogStatus, LogBytes, LogError},
+	}
+	r := NewRouter(WithInterceptors(AccessLog(opts)))
+	r.Get("/users/{id}", func(p *testUserPath) (string, error) {
+		if p.ID == 0 {
+			return "", fmt.Errorf("boom")
+		}
+		return "user", nil
+	})
+
+	cases := []struct {
+		target string
+		level  string
+		expect map[string]interface{}
+	}{
+		{"/users/7", "INFO", map[string]interface{}{
+			"method": "GET", "path": "/users/7", "pattern": "GET /users/{id}", "status": 200.0, "bytes": 4.0,
+		}},
+		{"/users/x", "INFO", map[string]interface{}{
+			"status": 400.0, "phase": "extract",
+		}},
+		{"/users/0", "ERROR", map[string]interface{}{
+			"status": 500.0, "error": "boom", "phase": "handler",
+		}},
+	}
+	for i, c := range cases {
+		buf.Reset()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", c.target, nil))
+		rec := map[string]interface{}{}
+		if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+			t.Fatalf("case %d: json.Unmarshal: %v: %s", i, err, buf.String())
+			return
+		}
+		if rec["msg"] != "access" || rec["level"] != c.level {
+			t.Fatalf("case %d: record is not expected: %s", i, buf.String())
+			return
+		}
+		for k, v := range c.expect {
+			if rec[k] != v {
+				t.Fatalf("case %d: expects %s=%v. got %v", i, k, v, rec[k])
+				return
+			}
+		}
+	}
+}
+
+func TestAccessLogSampling(t *testing.T) {
+	buf := &bytes.Buffer{}
+	opts := AccessLogOptions{
+		Logger: slog.New(slog.NewTextHandler(buf, nil)),
+		Sample: SampleRate(0),
+	}
+	ok := F(func() string { return "" }, WithInterceptors(AccessLog(opts)))
+	failed := F(func() error { return fmt.Errorf("x") }, WithInterceptors(AccessLog(opts)))
+	for i := 0; i < 10; i++ {
+		ok(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	failed(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	if n := strings.Count(buf.String(), "msg=access"); n != 1 {
+		t.Fatalf("expects only the failed request logged. got %d: %s", n, buf.String())
+		return
+	}
+}
@@ -0,0 +1,181 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+type argKind int
+
+const (
+	argRequest argKind = iota
+	argWriter
+	argContext
+	argBindable
+	argMultipart
+	argProvided
+	argValue
+)
+
+// argPlan describes how to produce one argument of a bind func.
+type argPlan struct {
+	kind   argKind
+	typ    reflect.Type // the element type if isPtr is true.
+	isPtr  bool
+	params *paramsPlan     // for struct values only.
+	valid  *validationPlan // for struct values only.
+}
+
+// funcPlan is the result of analysing a bind func once at registration time.
+type funcPlan struct {
+	fn        reflect.Value
+	typ       reflect.Type
+	args      []argPlan
+	results   []retKind
+	streaming bool // an argument reads the body as *multipart.Reader.
+	provided  bool // an argument is produced by a provider.
+}
+
+var (
+	typHttpReq = reflect.TypeOf((*http.Request)(nil))
+	typContext = reflect.TypeOf((*context.Context)(nil)).Elem()
+	typWriter  = reflect.TypeOf((*monitoredWriter)(nil))
+	typMpartR  = reflect.TypeOf((*multipart.Reader)(nil))
+)
+
+func compileFunc(fn interface{}, c *Container) (*funcPlan, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("nil binding func.")
+	}
+	typ := reflect.TypeOf(fn)
+	if typ.Kind() != reflect.Func {
+		return nil, fmt.Errorf("invalid binding func: %T is not a func.", fn)
+	}
+	if typ.IsVariadic() {
+		return nil, fmt.Errorf("invalid binding func: %v is variadic.", typ)
+	}
+	plan := &funcPlan{
+		fn:      reflect.ValueOf(fn),
+		typ:     typ,
+		args:    make([]argPlan, typ.NumIn()),
+		results: compileResults(typ),
+	}
+	for i := range plan.args {
+		a, err := compileArg(typ.In(i), c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid binding func: argument %d: %v", i, err)
+		}
+		plan.args[i] = a
+		plan.streaming = plan.streaming || a.kind == argMultipart
+		plan.provided = plan.provided || a.kind == argProvided
+	}
+	return plan, nil
+}
+
+func compileArg(typArg reflect.Type, c *Container) (argPlan, error) {
+	switch typArg {
+	case typHttpReq:
+		return argPlan{kind: argRequest, typ: typArg}, nil
+	case typWriter:
+		return argPlan{kind: argWriter, typ: typArg}, nil
+	case typMpartR:
+		return argPlan{kind: argMultipart, typ: typArg}, nil
+	}
+	if c != nil && c.lookup(typArg) != nil {
+		if err := c.check(typArg); err != nil {
+			return argPlan{}, err
+		}
+		return argPlan{kind: argProvided, typ: typArg}, nil
+	}
+	if typArg.Kind() == reflect.Interface {
+		if typWriter.Implements(typArg) {
+			return argPlan{kind: argWriter, typ: typArg}, nil
+		}
+		if typContext.Implements(typArg) {
+			return argPlan{kind: argContext, typ: typArg}, nil
+		}
+	}
+	isPtr := typArg.Kind() == reflect.Ptr
+	if isPtr {
+		typArg = typArg.Elem()
+	}
+	bindable := isPtr && reflect.PointerTo(typArg).Implements(typBindable)
+	bindable = bindable || (!isPtr && typArg.Implements(typBindable))
+	if bindable {
+		return argPlan{kind: argBindable, typ: typArg, isPtr: isPtr}, nil
+	}
+	a := argPlan{kind: argValue, typ: typArg, isPtr: isPtr}
+	if typArg.Kind() == reflect.Struct {
+		a.params = paramsPlanOf(typArg)
+		if a.params.err != nil {
+			return a, a.params.err
+		}
+		valid, err := validationPlanOf(typArg)
+		if err != nil {
+			return a, err
+		}
+		a.valid = valid
+	}
+	return a, nil
+}
+
+// phase is the Phase reported when resolving the argument fails.
+func (a *argPlan) phase() Phase {
+	if a.kind == argBindable || a.kind == argProvided {
+		return PhaseBind
+	}
+	return PhaseExtract
+}
+
+// resolve produces the argument value for a single request. s is nil
+// unless the func has provided arguments.
+// A non-nil error means the request should be answered with an error.
+func (a *argPlan) resolve(w *monitoredWriter, req *http.Request, x *valueExtractor, s *scope) (reflect.Value, error) {
+	switch a.kind {
+	case argProvided:
+		return s.get(a.typ)
+	case argRequest:
+		return reflect.ValueOf(req), nil
+	case argWriter:
+		return reflect.ValueOf(w), nil
+	case argContext:
+		return reflect.ValueOf(req.Context()), nil
+	case argMultipart:
+		mr, err := req.MultipartReader()
+		if err != nil {
+			return reflect.Zero(a.typ), WrapError(http.StatusUnsupportedMediaType, err, "multipart body expected.")
+		}
+		return reflect.ValueOf(mr), nil
+	case argBindable:
+		arg := reflect.New(a.typ)
+		var b Bindable
+		if a.isPtr {
+			b = arg.Interface().(Bindable)
+		} else {
+			b = arg.Elem().Interface().(Bindable)
+		}
+		if err := b.Bind(w, req); err != nil {
+			return arg, err
+		}
+		if a.isPtr {
+			return arg, nil
+		}
+		return arg.Elem(), nil
+	}
+	arg, err := x.newValueByType(a.typ, a.params)
+	if err != nil {
+		return arg, err
+	}
+	if a.valid != nil {
+		if err := a.valid.validate(arg.Elem()); err != nil {
+			return arg, err
+		}
+	}
+	if a.isPtr {
+		return arg, nil
+	}
+	return arg.Elem(), nil
+}
@@ -0,0 +1,167 @@
+package kit
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Converter converts a raw parameter to a value of the type it is
+// registered for.
+type Converter func(raw string) (interface{}, error)
+
+var converters sync.Map // reflect.Type -> Converter
+
+// RegisterConverter makes params of typ convert with conv, e.g. for ID or
+// enum types. It takes precedence over the built-in conversions.
+//
+// Converters are looked up when a type is first bound, so they should be
+// registered before the bind funcs using them are created.
+//
+//	kit.RegisterConverter(reflect.TypeOf(UserID(0)), func(s string) (interface{}, error) {
+//	    return ParseUserID(s)
+//	})
+func RegisterConverter(typ reflect.Type, conv Converter) {
+	if conv == nil {
+		converters.Delete(typ)
+		return
+	}
+	converters.Store(typ, conv)
+}
+
+func lookupConverter(typ reflect.Type) (Converter, bool) {
+	if c, ok := converters.Load(typ); ok {
+		return c.(Converter), true
+	}
+	return nil, false
+}
+
+// convertFunc sets fv from a raw string.
+type convertFunc func(fv reflect.Value, raw string) error
+
+var (
+	typTime     = reflect.TypeOf(time.Time{})
+	typDuration = reflect.TypeOf(time.Duration(0))
+	typString   = reflect.TypeOf("")
+)
+
+// DefaultTimeFormat is the layout of time.Time params without a format tag.
+var DefaultTimeFormat = time.RFC3339
+
+// converterFor returns the conversion of raw strings to typ. format is
+// the layout of time.Time values.
+func converterFor(typ reflect.Type, format string) convertFunc {
+	if conv, ok := lookupConverter(typ); ok {
+		return func(fv reflect.Value, raw string) error {
+			v, err := conv(raw)
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				return nil
+			}
+			if !assignDirect(fv, reflect.ValueOf(v)) {
+				return fmt.Errorf("converter returned %T for %v.", v, typ)
+			}
+			return nil
+		}
+	}
+	switch typ {
+	case typTime:
+		return func(fv reflect.Value, raw string) error {
+			layout := format
+			if len(layout) == 0 {
+				layout = DefaultTimeFormat
+			}
+			t, err := time.Parse(layout, raw)
+			if err == nil {
+				fv.Set(reflect.ValueOf(t))
+			}
+			return err
+		}
+	case typDuration:
+		return func(fv reflect.Value, raw string) error {
+			d, err := time.ParseDuration(raw)
+			if err == nil {
+				fv.SetInt(int64(d))
+			}
+			return err
+		}
+	}
+	if typ.Kind() == reflect.Ptr {
+		elem := typ.Elem()
+		convElem := converterFor(elem, format)
+		return func(fv reflect.Value, raw string) error {
+			p := reflect.New(elem)
+			if err := convElem(p.Elem(), raw); err != nil {
+				return err
+			}
+			fv.Set(p)
+			return nil
+		}
+	}
+	if reflect.PointerTo(typ).Implements(typTextUnmarshaler) {
+		return func(fv reflect.Value, raw string) error {
+			return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(raw))
+		}
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return func(fv reflect.Value, raw string) error {
+			fv.SetString(raw)
+			return nil
+		}
+	case reflect.Bool:
+		return func(fv reflect.Value, raw string) error {
+			b, err := strconv.ParseBool(raw)
+			if err == nil {
+				fv.SetBool(b)
+			}
+			return err
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(fv reflect.Value, raw string) error {
+			n, err := strconv.ParseInt(raw, 10, typ.Bits())
+			if err == nil {
+				fv.SetInt(n)
+			}
+			return err
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(fv reflect.Value, raw string) error {
+			n, err := strconv.ParseUint(raw, 10, typ.Bits())
+			if err == nil {
+				fv.SetUint(n)
+			}
+			return err
+		}
+	case reflect.Float32, reflect.Float64:
+		return func(fv reflect.Value, raw string) error {
+			f, err := strconv.ParseFloat(raw, typ.Bits())
+			if err == nil {
+				fv.SetFloat(f)
+			}
+			return err
+		}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return func(fv reflect.Value, raw string) error {
+				fv.SetBytes([]byte(raw))
+				return nil
+			}
+		}
+	}
+	if typString.AssignableTo(typ) || typString.ConvertibleTo(typ) {
+		// e.g. interface{}, which is given the raw string.
+		return func(fv reflect.Value, raw string) error {
+			assignDirect(fv, reflect.ValueOf(raw))
+			return nil
+		}
+	}
+	return func(fv reflect.Value, raw string) error {
+		return fmt.Errorf("unsupported type %v.", typ)
+	}
+}
@@ -0,0 +1,170 @@
+package kit
+
+import (
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testUserID int64
+
+func init() {
+	RegisterConverter(reflect.TypeOf(testUserID(0)), func(s string) (interface{}, error) {
+		var n int64
+		if _, err := fmt.Sscanf(s, "u-%d", &n); err != nil {
+			return nil, err
+		}
+		return testUserID(n), nil
+	})
+}
+
+type testScalars struct {
+	Active  bool          `req:"active"`
+	Big     int64         `req:"big"`
+	Size    uint64        `req:"size"`
+	Day     time.Time     `req:"day" format:"2006-01-02"`
+	At      *time.Time    `req:"at"`
+	Timeout time.Duration `req:"timeout"`
+	Limit   *int          `req:"limit"`
+	Offset  *int          `req:"offset"`
+	IP      net.IP        `req:"ip"`
+	User    testUserID    `req:"user"`
+	Users   []testUserID  `req:"users,comma"`
+}
+
+func TestParamsConvert(t *testing.T) {
+	h := F(func(p *testScalars) string {
+		return strings.Join([]string{
+			fmt.Sprint(p.Active), fmt.Sprint(p.Big), fmt.Sprint(p.Size),
+			p.Day.Format("Jan 2 2006"), p.At.UTC().Format(time.Kitchen),
+			p.Timeout.String(), fmt.Sprint(*p.Limit), fmt.Sprint(p.Offset == nil),
+			p.IP.String(), fmt.Sprint(p.User), fmt.Sprint(p.Users),
+		}, "|")
+	})
+
+	qry := "active=true&big=9007199254740993&size=18446744073709551615" +
+		"&day=2024-02-29&at=2024-01-01T15:04:00Z&timeout=1m30s&limit=0" +
+		"&ip=10.0.0.1&user=u-42&users=u-1,u-2"
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?"+qry, nil))
+	expected := "true|9007199254740993|18446744073709551615|Feb 29 2024|3:04PM|1m30s|0|true|10.0.0.1|42|[1 2]"
+	if w.Body.String() != expected {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+	}
+}
+
+func TestParamsConvertStrict(t *testing.T) {
+	type query struct {
+		Page int       `query:"page"`
+		Day  time.Time `req:"day" format:"2006-01-02"`
+		Tags []int     `req:"tag"`
+	}
+	fn := func(q *query) string { return fmt.Sprint(q.Page) }
+
+	w := httptest.NewRecorder()
+	F(fn, WithErrorRenderer(ProblemRenderer))(w, httptest.NewRequest("GET", "/?page=abc&day=tomorrow&tag=1&tag=x", nil))
+	if w.Code != 400 {
+		t.Fatalf("expects 400. got %d", w.Code)
+		return
+	}
+	for _, s := range []string{`"field":"page","source":"query","value":"abc","type":"int"`, `"field":"day"`, `"value":"1,x"`} {
+		if !strings.Contains(w.Body.String(), s) {
+			t.Fatalf("expects %s in %s", s, w.Body.String())
+			return
+		}
+	}
+
+	w = httptest.NewRecorder()
+	F(fn, WithLenientParams())(w, httptest.NewRequest("GET", "/?page=abc", nil))
+	if w.Code != 200 || w.Body.String() != "0" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	q := query{}
+	err := UnmarshalParamsStrict(&q, func(name string) interface{} {
+		return map[string]interface{}{"page": "7", "day": "x"}[name]
+	})
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 1 || errs[0].Field != "day" || q.Page != 7 {
+		t.Fatalf("unexpected result: %v %v", err, q)
+	}
+}
+
+func TestParamsConvertJSONStrict(t *testing.T) {
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"x":"8","y":9}`))
+	req.Header.Set("Content-Type", "application/json")
+	F(func(p *testPayload) int { return p.X * p.Y })(w, req)
+	if w.Code != 400 || !strings.Contains(w.Body.String(), "x: invalid value") {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+	}
+}
+
+type testDefaults struct {
+	Q     string     `req:"q" default:"all"`
+	Limit int        `req:"limit" default:"20"`
+	Tags  []string   `req:"tag" default:"a,b"`
+	Since time.Time  `req:"since" format:"2006-01-02" default:"2024-01-01"`
+	Geo   testGeo    `req:"geo"`
+	Opt   *testGeoDf `req:"opt"`
+}
+
+type testGeoDf struct {
+	Lat float64 `req:"lat" default:"1.5"`
+	Lng float64 `req:"lng"`
+}
+
+func TestParamsDefaults(t *testing.T) {
+	fn := func(p *testDefaults) string {
+		return fmt.Sprintf("%q|%d|%v|%s|%v", p.Q, p.Limit, p.Tags, p.Since.Format("2006-01-02"), p.Opt)
+	}
+	cases := map[string]string{
+		"/":                  `"all"|20|[a b]|2024-01-01|<nil>`,
+		"/?q=&limit=5&tag=x": `""|5|[x]|2024-01-01|<nil>`,
+		"/?since=2020-05-06": `"all"|20|[a b]|2020-05-06|<nil>`,
+		"/?opt.lng=3":        `"all"|20|[a b]|2024-01-01|&{1.5 3}`,
+	}
+	for uri, expected := range cases {
+		w := httptest.NewRecorder()
+		F(fn)(w, httptest.NewRequest("GET", uri, nil))
+		if w.Body.String() != expected {
+			t.Fatalf("%s: body is not expected: %s", uri, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestParamsDefaultsInvalid(t *testing.T) {
+	type bad struct {
+		N int `req:"n" default:"many"`
+	}
+	if err := UnmarshalParams(&bad{}, func(string) interface{} { return nil }); err == nil {
+		t.Fatalf("expects an error for invalid default.")
+	}
+}
+
+func TestParamsConvertRaw(t *testing.T) {
+	type raw struct {
+		V interface{} `req:"v"`
+	}
+	h := F(func(p *raw) string {
+		return fmt.Sprintf("%T %v", p.V, p.V)
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?v=hello", nil))
+	if w.Code != 200 || w.Body.String() != "string hello" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	p := &raw{}
+	if err := UnmarshalParams(p, func(name string) interface{} { return name }); err != nil || p.V != "v" {
+		t.Fatalf("UnmarshalParams: %v %v", p.V, err)
+		return
+	}
+}
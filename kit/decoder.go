@@ -0,0 +1,103 @@
+package kit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Decoder fills target, a pointer, from a request body.
+// It returns ErrUnsupportedMediaType if it can't fill targets of that type.
+type Decoder func(body []byte, target interface{}) error
+
+// ErrUnsupportedMediaType is answered with 415 when a bound func expects a
+// body in a media type no Decoder handles.
+var ErrUnsupportedMediaType = NewError(http.StatusUnsupportedMediaType, "")
+
+var decoders sync.Map // media type -> Decoder
+
+func init() {
+	RegisterDecoder("application/json", DecodeJSON)
+	RegisterDecoder("application/xml", DecodeXML)
+	RegisterDecoder("text/xml", DecodeXML)
+	RegisterDecoder("application/x-www-form-urlencoded", DecodeForm)
+	RegisterDecoder("text/plain", DecodeText)
+	RegisterDecoder("application/octet-stream", DecodeBytes)
+}
+
+// RegisterDecoder makes request bodies of mediaType decode with dec.
+// A nil dec removes the decoder.
+func RegisterDecoder(mediaType string, dec Decoder) {
+	mediaType = strings.ToLower(mediaType)
+	if dec == nil {
+		decoders.Delete(mediaType)
+		return
+	}
+	decoders.Store(mediaType, dec)
+}
+
+// lookupDecoder finds the decoder of mediaType. Structured syntax suffixes
+// like application/problem+json fall back to the decoder of their suffix.
+func lookupDecoder(mediaType string) (Decoder, bool) {
+	if d, ok := decoders.Load(mediaType); ok {
+		return d.(Decoder), true
+	}
+	if i := strings.LastIndex(mediaType, "+"); i >= 0 {
+		return lookupDecoder("application/" + mediaType[i+1:])
+	}
+	return nil, false
+}
+
+// DecodeJSON decodes a JSON body.
+func DecodeJSON(body []byte, target interface{}) error {
+	return jsonBindError(json.Unmarshal(body, target))
+}
+
+// DecodeXML decodes a XML body.
+func DecodeXML(body []byte, target interface{}) error {
+	return xml.Unmarshal(body, target)
+}
+
+// DecodeForm decodes an urlencoded form body into a struct like
+// UnmarshalParamsStrict.
+func DecodeForm(body []byte, target interface{}) error {
+	tv := reflect.ValueOf(target)
+	if tv.Kind() != reflect.Ptr || tv.Elem().Kind() != reflect.Struct {
+		return ErrUnsupportedMediaType
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return WrapError(http.StatusBadRequest, err, "invalid form body.")
+	}
+	return UnmarshalParamsFrom(target, func(src Source, name string) interface{} {
+		switch src {
+		case SourceAny, SourceForm:
+			return lookupValues(values, name)
+		}
+		return nil
+	}, true)
+}
+
+// DecodeText sets a *string or *[]byte to the body.
+func DecodeText(body []byte, target interface{}) error {
+	tv := reflect.ValueOf(target)
+	if tv.Kind() == reflect.Ptr && tv.Elem().Kind() == reflect.String {
+		tv.Elem().SetString(string(body))
+		return nil
+	}
+	return DecodeBytes(body, target)
+}
+
+// DecodeBytes sets a *[]byte to the body.
+func DecodeBytes(body []byte, target interface{}) error {
+	tv := reflect.ValueOf(target)
+	if tv.Kind() == reflect.Ptr && tv.Elem().Kind() == reflect.Slice && tv.Elem().Type().Elem().Kind() == reflect.Uint8 {
+		tv.Elem().SetBytes(body)
+		return nil
+	}
+	return ErrUnsupportedMediaType
+}
@@ -0,0 +1,73 @@
+package kit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testXY struct {
+	X int `json:"x" xml:"x" req:"x"`
+	Y int `json:"y" xml:"y" req:"y"`
+}
+
+func TestBodyDecoders(t *testing.T) {
+	mul := F(func(p *testXY) int { return p.X * p.Y })
+	text := F(func(s string) string { return strings.ToUpper(s) })
+	raw := F(func(b []byte) int { return len(b) })
+	list := F(func(items []testXY) int { return len(items) })
+
+	cases := []struct {
+		h      http.HandlerFunc
+		method string
+		ct     string
+		body   string
+		status int
+		out    string
+	}{
+		{mul, "POST", "application/json", `{"x":8,"y":9}`, 200, "72"},
+		{mul, "POST", "application/xml", `<item><x>3</x><y>4</y></item>`, 200, "12"},
+		{mul, "PUT", "application/x-www-form-urlencoded", `x=2&y=5`, 200, "10"},
+		{mul, "DELETE", "application/x-www-form-urlencoded", `x=2&y=6`, 200, "12"},
+		{mul, "POST", "application/vnd.acme+json", `{"x":1,"y":9}`, 200, "9"},
+		{mul, "POST", "text/csv", "x,y\n1,2", 415, ""},
+		{mul, "POST", "text/plain", "hello", 415, ""},
+		{text, "POST", "text/plain", "hi", 200, "HI"},
+		{raw, "POST", "application/octet-stream", "\x00\x01\x02", 200, "3"},
+		{list, "POST", "application/json", `[{"x":1},{"x":2}]`, 200, "2"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method, "/", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", c.ct)
+		c.h(w, req)
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d: %s", i, c.status, w.Code, w.Body.String())
+			return
+		}
+		if len(c.out) > 0 && w.Body.String() != c.out {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestFormBodyKeepsParams(t *testing.T) {
+	type page struct {
+		ID    int `req:"id"`
+		Limit int `req:"limit" default:"20"`
+		X     int `req:"x"`
+	}
+	h := F(func(p *page) []int { return []int{p.ID, p.Limit, p.X} })
+	for _, method := range []string{"DELETE", "POST"} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/?limit=5&id=3", strings.NewReader("x=1"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		h(w, req)
+		if w.Code != 200 || w.Body.String() != "[3,5,1]" {
+			t.Fatalf("%s: unexpected response: %d %s", method, w.Code, w.Body.String())
+			return
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package kit
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Encoder writes values returned by bound funcs in a media type.
+type Encoder interface {
+	// ContentType is the Content-Type header of the output, e.g.
+	// "text/plain; charset=UTF-8". Its media type is what Accept is
+	// matched against.
+	ContentType() string
+
+	// CanEncode reports if v can be written by Encode.
+	CanEncode(v reflect.Value) bool
+
+	Encode(w io.Writer, v reflect.Value) error
+}
+
+var (
+	JSONEncoder Encoder = jsonEncoder{}
+	XMLEncoder  Encoder = xmlEncoder{}
+	TextEncoder Encoder = textEncoder{}
+	CSVEncoder  Encoder = csvEncoder{}
+)
+
+type encoderEntry struct {
+	mediaType string
+	enc       Encoder
+}
+
+var (
+	encodersLck = new(sync.RWMutex)
+	encoders    = []encoderEntry{}
+)
+
+func init() {
+	RegisterEncoder(JSONEncoder)
+	RegisterEncoder(XMLEncoder)
+	RegisterEncoder(TextEncoder)
+	RegisterEncoder(CSVEncoder)
+}
+
+// RegisterEncoder adds enc to the encoders bound funcs choose from by the
+// Accept header of requests. It replaces an encoder of the same media type.
+func RegisterEncoder(enc Encoder) {
+	mt := mediaTypeOf(enc.ContentType())
+	encodersLck.Lock()
+	defer encodersLck.Unlock()
+	for i, e := range encoders {
+		if e.mediaType == mt {
+			encoders[i].enc = enc
+			return
+		}
+	}
+	encoders = append(encoders, encoderEntry{mediaType: mt, enc: enc})
+}
+
+func mediaTypeOf(ct string) string {
+	if mt, _, err := mime.ParseMediaType(ct); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(ct, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+// defaultEncoder is used without an Accept header: text for strings, JSON
+// for anything else.
+func defaultEncoder(v reflect.Value) Encoder {
+	if v.Kind() == reflect.String {
+		return TextEncoder
+	}
+	return JSONEncoder
+}
+
+type acceptRange struct {
+	typ, sub string
+	q        float64
+}
+
+// parseAccept parses an Accept header. Ranges are sorted by specificity,
+// most specific first.
+func parseAccept(accept string) []acceptRange {
+	ranges := []acceptRange{}
+	for _, item := range strings.Split(accept, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		mt, params, err := mime.ParseMediaType(item)
+		if err != nil {
+			continue
+		}
+		if mt == "*" {
+			mt = "*/*"
+		}
+		typ, sub, ok := strings.Cut(mt, "/")
+		if !ok {
+			continue
+		}
+		r := acceptRange{typ: typ, sub: sub, q: 1}
+		if qs, ok := params["q"]; ok {
+			if q, err := strconv.ParseFloat(qs, 64); err == nil {
+				r.q = q
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	specificity := func(r acceptRange) int {
+		if r.typ == "*" {
+			return 0
+		} else if r.sub == "*" {
+			return 1
+		}
+		return 2
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return specificity(ranges[i]) > specificity(ranges[j])
+	})
+	return ranges
+}
+
+// quality is the q value of the most specific range matching mediaType.
+func quality(ranges []acceptRange, mediaType string) float64 {
+	typ, sub, _ := strings.Cut(mediaType, "/")
+	for _, r := range ranges {
+		if (r.typ == "*" || r.typ == typ) && (r.sub == "*" || r.sub == sub) {
+			return r.q
+		}
+	}
+	return 0
+}
+
+// defaultPreference is how much lower the q value of the default encoder
+// may be than the best one and still be chosen. Browsers accept */* a bit
+// below XML, which shouldn't turn every answer into XML.
+const defaultPreference = 0.1
+
+// negotiateEncoders lists the encoders for v acceptable by the Accept
+// header, the preferred one first. The default encoder comes first if its
+// q value is within defaultPreference of the best. It returns nil if none
+// is acceptable.
+func negotiateEncoders(accept string, v reflect.Value) []Encoder {
+	def := defaultEncoder(v)
+	if len(strings.TrimSpace(accept)) == 0 {
+		return []Encoder{def}
+	}
+	ranges := parseAccept(accept)
+
+	encodersLck.RLock()
+	candidates := make([]encoderEntry, 0, len(encoders)+1)
+	candidates = append(candidates, encoderEntry{mediaTypeOf(def.ContentType()), def})
+	candidates = append(candidates, encoders...)
+	encodersLck.RUnlock()
+
+	type scored struct {
+		enc Encoder
+		q   float64
+	}
+	acceptable := []scored{}
+	bestQ := 0.0
+	for _, c := range candidates {
+		q := quality(ranges, c.mediaType)
+		if q > 0 && c.enc.CanEncode(v) {
+			acceptable = append(acceptable, scored{c.enc, q})
+			bestQ = max(bestQ, q)
+		}
+	}
+	if len(acceptable) == 0 {
+		return nil
+	}
+	if acceptable[0].enc == def && acceptable[0].q+defaultPreference >= bestQ {
+		acceptable[0].q = bestQ
+	}
+	sort.SliceStable(acceptable, func(i, j int) bool {
+		return acceptable[i].q > acceptable[j].q
+	})
+	encs := make([]Encoder, len(acceptable))
+	for i, c := range acceptable {
+		encs[i] = c.enc
+	}
+	return encs
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) ContentType() string { return "application/json" }
+
+func (jsonEncoder) CanEncode(v reflect.Value) bool { return v.CanInterface() }
+
+func (jsonEncoder) Encode(w io.Writer, v reflect.Value) error {
+	dat, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(dat)
+	return err
+}
+
+type xmlEncoder struct{}
+
+func (xmlEncoder) ContentType() string { return "application/xml; charset=UTF-8" }
+
+func (xmlEncoder) CanEncode(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		// would be several top-level elements without a root.
+		return false
+	}
+	return v.CanInterface() && xmlEncodable(v.Type())
+}
+
+var (
+	typXMLMarshaler = reflect.TypeOf((*xml.Marshaler)(nil)).Elem()
+	xmlEncodables   sync.Map // reflect.Type -> bool
+)
+
+// xmlEncodable reports whether xml.Marshal supports typ, looking into
+// the fields of structs. Values of interface fields are left to Encode.
+func xmlEncodable(typ reflect.Type) bool {
+	if ok, found := xmlEncodables.Load(typ); found {
+		return ok.(bool)
+	}
+	ok := xmlEncodableType(typ, map[reflect.Type]bool{})
+	xmlEncodables.Store(typ, ok)
+	return ok
+}
+
+func xmlEncodableType(typ reflect.Type, seen map[reflect.Type]bool) bool {
+	for _, t := range []reflect.Type{typ, reflect.PointerTo(typ)} {
+		if t.Implements(typXMLMarshaler) || t.Implements(typTextMarshaler) {
+			return true
+		}
+	}
+	switch typ.Kind() {
+	case reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer,
+		reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return xmlEncodableType(typ.Elem(), seen)
+	case reflect.Struct:
+		if seen[typ] {
+			return true
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			f := typ.Field(i)
+			if !f.IsExported() && !f.Anonymous || f.Tag.Get("xml") == "-" {
+				continue
+			}
+			if !xmlEncodableType(f.Type, seen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (xmlEncoder) Encode(w io.Writer, v reflect.Value) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(v.Interface())
+}
+
+type textEncoder struct{}
+
+func (textEncoder) ContentType() string { return "text/plain; charset=UTF-8" }
+
+var (
+	typStringer      = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	typTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func (textEncoder) CanEncode(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return v.Type().Implements(typTextMarshaler) || v.Type().Implements(typStringer)
+}
+
+func (textEncoder) Encode(w io.Writer, v reflect.Value) error {
+	if v.Kind() == reflect.String {
+		_, err := io.WriteString(w, v.String())
+		return err
+	}
+	if tm, ok := v.Interface().(encoding.TextMarshaler); ok {
+		dat, err := tm.MarshalText()
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(dat)
+		return err
+	}
+	_, err := fmt.Fprint(w, v.Interface())
+	return err
+}
+
+// csvEncoder writes slices of structs, one row per item after a header
+// row of field names. Fields are named by their csv or json tag.
+type csvEncoder struct{}
+
+func (csvEncoder) ContentType() string { return "text/csv; charset=UTF-8" }
+
+func csvRowType(typ reflect.Type) (reflect.Type, bool) {
+	if typ.Kind() != reflect.Slice && typ.Kind() != reflect.Array {
+		return nil, false
+	}
+	elem := typ.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	return elem, elem.Kind() == reflect.Struct
+}
+
+func (csvEncoder) CanEncode(v reflect.Value) bool {
+	_, ok := csvRowType(v.Type())
+	return ok
+}
+
+func (csvEncoder) Encode(w io.Writer, v reflect.Value) error {
+	elem, _ := csvRowType(v.Type())
+	columns := []int{}
+	header := []string{}
+	for i := 0; i < elem.NumField(); i++ {
+		f := elem.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		for _, tag := range []string{"csv", "json"} {
+			if n, _, _ := strings.Cut(f.Tag.Get(tag), ","); len(n) > 0 {
+				name = n
+				break
+			}
+		}
+		if name == "-" {
+			continue
+		}
+		columns = append(columns, i)
+		header = append(header, name)
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		if item.Kind() == reflect.Ptr {
+			if item.IsNil() {
+				continue
+			}
+			item = item.Elem()
+		}
+		for j, col := range columns {
+			fv := item.Field(col)
+			if tm, ok := fv.Interface().(encoding.TextMarshaler); ok {
+				dat, err := tm.MarshalText()
+				if err != nil {
+					return err
+				}
+				row[j] = string(dat)
+			} else {
+				row[j] = fmt.Sprint(fv.Interface())
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// errNotAcceptable is answered when no encoder matches the Accept header.
+var errNotAcceptable = NewError(http.StatusNotAcceptable, "")
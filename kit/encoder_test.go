@@ -0,0 +1,84 @@
+package kit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testRow struct {
+	ID   int    `json:"id" xml:"id,attr"`
+	Name string `json:"name" xml:"name"`
+}
+
+type testLabelled struct {
+	Labels map[string]string
+}
+
+type testDynamic struct {
+	Extra interface{}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	rows := F(func() []testRow {
+		return []testRow{{1, "a,b"}, {2, "c"}}
+	})
+	text := F(func() string { return "hello" })
+	row := F(func() testRow { return testRow{1, "a"} })
+	labelled := F(func() testLabelled { return testLabelled{Labels: map[string]string{"a": "b"}} })
+	dynamic := F(func() testDynamic { return testDynamic{Extra: map[string]int{"a": 1}} })
+	browser := "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+
+	cases := []struct {
+		h      http.HandlerFunc
+		accept string
+		status int
+		ct     string
+		body   string
+	}{
+		{rows, "", 200, "application/json", `[{"id":1,"name":"a,b"},{"id":2,"name":"c"}]`},
+		{rows, "text/csv", 200, "text/csv; charset=UTF-8", "id,name\n1,\"a,b\"\n2,c\n"},
+		{rows, "application/xml;q=0.5, application/json", 200, "application/json", ""},
+		{rows, "text/html, application/*;q=0.2", 200, "application/json", ""},
+		{rows, "text/plain", 406, "", ""},
+		{rows, "application/xml", 406, "", ""},
+		{rows, "application/xml, application/json;q=0.5", 200, "application/json", ""},
+		{text, "*/*", 200, "text/plain; charset=UTF-8", "hello"},
+		{text, "application/json", 200, "application/json", `"hello"`},
+		{text, "text/plain;q=0, */*", 200, "application/json", `"hello"`},
+		{text, "application/xml", 200, "application/xml; charset=UTF-8", `<?xml version="1.0" encoding="UTF-8"?>` + "\n<string>hello</string>"},
+		{text, browser, 200, "text/plain; charset=UTF-8", "hello"},
+		{row, browser, 200, "application/json", `{"id":1,"name":"a"}`},
+		{row, "application/xml, */*;q=0.5", 200, "application/xml; charset=UTF-8", ""},
+		{labelled, browser, 200, "application/json", `{"Labels":{"a":"b"}}`},
+		{labelled, "application/xml", 406, "", ""},
+		{dynamic, "application/xml, application/json;q=0.1", 200, "application/json", `{"Extra":{"a":1}}`},
+	}
+	for i, c := range cases {
+		req := httptest.NewRequest("GET", "/", nil)
+		if len(c.accept) > 0 {
+			req.Header.Set("Accept", c.accept)
+		}
+		w := httptest.NewRecorder()
+		c.h(w, req)
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d", i, c.status, w.Code)
+			return
+		}
+		if c.status != 200 {
+			continue
+		}
+		if ct := w.Header().Get("Content-Type"); ct != c.ct {
+			t.Fatalf("case %d: unexpected content type: %s", i, ct)
+			return
+		}
+		if w.Header().Get("Vary") != "Accept" {
+			t.Fatalf("case %d: Vary: Accept is missing.", i)
+			return
+		}
+		if len(c.body) > 0 && w.Body.String() != c.body {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package kit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// DefaultErrorStatus is the status code used when a bound func returns an
+// error that doesn't implement StatusError.
+var DefaultErrorStatus = http.StatusInternalServerError
+
+// DefaultBindErrorStatus is the status code used when a Bindable or the
+// payload extraction fails with an error that doesn't implement StatusError.
+var DefaultBindErrorStatus = http.StatusBadRequest
+
+// StatusError is an error that knows which HTTP status it should be answered with.
+//
+// An error may optionally implement
+//
+//	Headers() http.Header    // extra headers written with the response.
+//	PublicMessage() string   // the message sent to the client instead of Error().
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+type headersCarrier interface {
+	Headers() http.Header
+}
+
+type publicMessager interface {
+	PublicMessage() string
+}
+
+// HTTPError is a ready to use StatusError.
+type HTTPError struct {
+	Status  int
+	Message string      // public message, sent to the client.
+	Header  http.Header // optional headers.
+	Cause   error       // internal cause, never sent to the client.
+}
+
+var _ StatusError = (*HTTPError)(nil)
+
+// NewError creates a HTTPError with status code and public message.
+func NewError(status int, msg string) *HTTPError {
+	return &HTTPError{Status: status, Message: msg}
+}
+
+// WrapError creates a HTTPError with an internal cause.
+func WrapError(status int, cause error, msg string) *HTTPError {
+	return &HTTPError{Status: status, Message: msg, Cause: cause}
+}
+
+func (e *HTTPError) Error() string {
+	msg := e.PublicMessage()
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", msg, e.Cause)
+	}
+	return msg
+}
+
+func (e *HTTPError) StatusCode() int {
+	return e.Status
+}
+
+func (e *HTTPError) Headers() http.Header {
+	return e.Header
+}
+
+func (e *HTTPError) PublicMessage() string {
+	if len(e.Message) > 0 {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorStatus returns the status code carried by err, or fallback if there is none.
+func ErrorStatus(err error, fallback int) int {
+	var se StatusError
+	if errors.As(err, &se) {
+		if code := se.StatusCode(); code > 0 {
+			return code
+		}
+	}
+	return fallback
+}
+
+// errorMessage is the message of err sent to the client with status: its
+// public message if any. Otherwise the text of a server error is only
+// logged, and the client gets the status text.
+func errorMessage(req *http.Request, err error, status int) string {
+	var pm publicMessager
+	if errors.As(err, &pm) {
+		return pm.PublicMessage()
+	}
+	if status >= 500 {
+		args := []interface{}{"status", status, "err", err}
+		if req != nil {
+			args = append(args, "method", req.Method, "path", req.URL.Path)
+		}
+		slog.Error("kit: internal error:", args...)
+		return http.StatusText(status)
+	}
+	return fmt.Sprintf("%v", err)
+}
+
+// BindError tells that a request param failed to convert to its field.
+type BindError struct {
+	Field  string `json:"field"`
+	Source Source `json:"source,omitempty"`
+	Value  string `json:"value"`
+	Type   string `json:"type"` // the expected type.
+	Err    error  `json:"-"`
+}
+
+var _ StatusError = (*BindError)(nil)
+
+func newBindError(k paramKey, typ reflect.Type, sv interface{}, err error) *BindError {
+	value := ""
+	switch v := sv.(type) {
+	case string:
+		value = v
+	case []string:
+		value = strings.Join(v, ",")
+	default:
+		value = fmt.Sprintf("%v", v)
+	}
+	return &BindError{Field: k.name, Source: k.src, Value: value, Type: typ.String(), Err: err}
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("%s: invalid value %q for %s", e.Field, e.Value, e.Type)
+}
+
+func (e *BindError) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// BindErrors lists every param of a request which failed to convert.
+// It is answered with status 400.
+type BindErrors []*BindError
+
+var _ StatusError = (BindErrors)(nil)
+var _ ProblemExtender = (BindErrors)(nil)
+
+func (e BindErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, be := range e {
+		msgs[i] = be.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e BindErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (e BindErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, be := range e {
+		errs[i] = be
+	}
+	return errs
+}
+
+func (e BindErrors) ProblemExtensions() map[string]interface{} {
+	return map[string]interface{}{"errors": []*BindError(e)}
+}
+
+// jsonBindError turns a type mismatch of the JSON body into a BindError.
+func jsonBindError(err error) error {
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) {
+		return BindErrors{{
+			Field:  te.Field,
+			Source: SourceBody,
+			Value:  te.Value,
+			Type:   te.Type.String(),
+			Err:    err,
+		}}
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Handle binds fn like BindFunc, with a signature checked by the compiler.
+// in is built from the request like a payload argument of BindFunc, and
+// out is written like a result of BindFunc. fn is called directly rather
+// than by reflection.
+func Handle[In, Out any](fn func(ctx context.Context, in In) (Out, error), opts ...Option) http.HandlerFunc {
+	return bindFunc("Handle", fn, func(args []reflect.Value) []reflect.Value {
+		in, _ := args[1].Interface().(In)
+		out, err := fn(args[0].Interface().(context.Context), in)
+		return typedResults(out, err)
+	}, opts)
+}
+
+// HandleSession is Handle with a session S resolved before in, which must
+// be a Bindable or a provided type. See Container.
+func HandleSession[S, In, Out any](fn func(ctx context.Context, s S, in In) (Out, error), opts ...Option) http.HandlerFunc {
+	h := bindFunc("HandleSession", fn, func(args []reflect.Value) []reflect.Value {
+		s, _ := args[1].Interface().(S)
+		in, _ := args[2].Interface().(In)
+		out, err := fn(args[0].Interface().(context.Context), s, in)
+		return typedResults(out, err)
+	}, opts)
+	a, _ := compileArg(reflect.TypeOf((*S)(nil)).Elem(), newBindOptions(opts).providers())
+	if a.kind != argBindable && a.kind != argProvided {
+		panic(fmt.Sprintf("kit.HandleSession: %v is neither Bindable nor provided.", a.typ))
+	}
+	return h
+}
+
+func typedResults[Out any](out Out, err error) []reflect.Value {
+	return []reflect.Value{reflect.ValueOf(&out).Elem(), reflect.ValueOf(&err).Elem()}
+}
@@ -0,0 +1,77 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandle(t *testing.T) {
+	mul := Handle(func(ctx context.Context, p *testXY) (int, error) {
+		if p.X < 0 {
+			return 0, NewError(422, "negative.")
+		}
+		return p.X * p.Y, nil
+	})
+	who := HandleSession(func(ctx context.Context, s *benchSession, p testXY) (*Response, error) {
+		return &Response{Status: 201, Body: fmt.Sprintf("%s %d", s.Uid, p.X)}, nil
+	})
+	denied := HandleSession(func(ctx context.Context, s *testDeniedSession, p struct{}) (string, error) {
+		return "ok", nil
+	})
+
+	cases := []struct {
+		h      func(w *httptest.ResponseRecorder)
+		status int
+		out    string
+	}{
+		{func(w *httptest.ResponseRecorder) { mul(w, httptest.NewRequest("GET", "/?x=8&y=9", nil)) }, 200, "72"},
+		{func(w *httptest.ResponseRecorder) { mul(w, httptest.NewRequest("GET", "/?x=-1", nil)) }, 422, "negative."},
+		{func(w *httptest.ResponseRecorder) { mul(w, httptest.NewRequest("GET", "/?x=a", nil)) }, 400, ""},
+		{func(w *httptest.ResponseRecorder) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(`{"x":3}`))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("x-uid", "amy")
+			who(w, req)
+		}, 201, "amy 3"},
+		{func(w *httptest.ResponseRecorder) { denied(w, httptest.NewRequest("GET", "/", nil)) }, 401, "login required."},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		c.h(w)
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d: %s", i, c.status, w.Code, w.Body.String())
+			return
+		}
+		if len(c.out) > 0 && w.Body.String() != c.out {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestHandleSessionInvalid(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("a session which is not Bindable is accepted.")
+		}
+	}()
+	HandleSession(func(ctx context.Context, s *testXY, p struct{}) (string, error) {
+		return "", nil
+	})
+}
+
+func BenchmarkHandle(b *testing.B) {
+	h := HandleSession(func(ctx context.Context, s *benchSession, p *testPayload) (int, error) {
+		return p.X * p.Y, nil
+	})
+	req := httptest.NewRequest("GET", "/mul?x=8&y=9", nil)
+	req.Header.Set("x-uid", "bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h(httptest.NewRecorder(), req)
+	}
+}
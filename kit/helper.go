@@ -1,13 +1,12 @@
 package kit
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"reflect"
-	"strconv"
-	"strings"
 )
 
 func simple(w http.ResponseWriter, code int, msg string) {
@@ -17,51 +16,68 @@ func simple(w http.ResponseWriter, code int, msg string) {
 }
 
 // JSON writes value v as JSON to response body.
+// A marshaling failure is written through DefaultErrorRenderer.
 func JSON(w http.ResponseWriter, code int, v interface{}) {
 	if dat, err := json.Marshal(v); err != nil {
-		http.Error(w, "json marshaling error.", 419)
 		slog.Warn("json.Marshal:", "err", err)
+		defaultErrorRenderer()(w, nil, err, PhaseRender)
 	} else {
 		w.Write(dat)
 	}
 }
 
-// Event writes a SSE entry.
+// Event writes a SSE entry. See EventStream for ids, retries and comments.
 func Event(w http.ResponseWriter, event, data string) {
-	if len(event) > 0 {
-		fmt.Fprintf(w, "event: %s\r\n", event)
-	}
-	if strings.Index(data, "\n") >= 0 {
-		lines := strings.Split(data, "\n")
-		for i, line := range lines {
-			lines[i] = strings.Trim(line, "\r\n")
-			fmt.Fprintf(w, "data: %s\r\n", data)
-		}
-	} else {
-		fmt.Fprintf(w, "data: %s\r\n", data)
-	}
-	fmt.Fprintf(w, "\r\n")
-	if flusher, ok := w.(http.Flusher); ok {
-		flusher.Flush()
+	if err := NewEventStream(w).Send(ServerEvent{Event: event, Data: data}); err != nil {
+		slog.Warn("event:", "err", err)
 	}
 }
 
+// WriteAsResponseAuto writes val as text/plain if it is a string, otherwise as JSON.
+// A marshaling failure is written through DefaultErrorRenderer.
 func WriteAsResponseAuto(w http.ResponseWriter, val reflect.Value) {
-	switch val.Type().Kind() {
-	case reflect.String:
-		w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
-		w.Write([]byte(val.String()))
-	default:
-		if val.CanInterface() {
-			w.Header().Set("Content-Type", "application/json")
-			vi := val.Interface()
-			if dat, err := json.Marshal(vi); err != nil {
-				slog.Warn("json.Marshal:", "value", vi, "err", err)
-			} else {
-				w.Write(dat)
-			}
+	writeAuto(w, nil, nil, val, defaultErrorRenderer())
+}
+
+// writeAuto writes val with the status, headers and cookies of res, if
+// any. Streamed types are written by writeStream. Other values are
+// encoded by the encoder chosen by the Accept header of req, or by the
+// kind of val if req is nil.
+func writeAuto(w http.ResponseWriter, req *http.Request, res *Response, val reflect.Value, render ErrorRenderer) {
+	if writeStream(w, req, res, val) {
+		return
+	}
+	if !val.CanInterface() {
+		return
+	}
+	encs := []Encoder{defaultEncoder(val)}
+	if req != nil {
+		w.Header().Add("Vary", "Accept")
+		if encs = negotiateEncoders(req.Header.Get("Accept"), val); len(encs) == 0 {
+			render(w, req, errNotAcceptable, PhaseRender)
+			return
+		}
+	}
+	// a failing encoder gives way to the next acceptable one.
+	buf := new(bytes.Buffer)
+	var enc Encoder
+	var err error
+	for _, enc = range encs {
+		buf.Reset()
+		if err = enc.Encode(buf, val); err == nil {
+			break
 		}
+		slog.Warn("encode:", "content-type", enc.ContentType(), "err", err)
 	}
+	if err != nil {
+		render(w, req, err, PhaseRender)
+		return
+	}
+	w.Header().Set("Content-Type", enc.ContentType())
+	if res != nil {
+		res.writeHeader(w)
+	}
+	w.Write(buf.Bytes())
 }
 
 func ValueToError(v reflect.Value) (error, bool) {
@@ -79,17 +95,45 @@ func ValueToError(v reflect.Value) (error, bool) {
 	return nil, false
 }
 
+// ParamFinder returns the value of a named parameter, or nil if there is none.
 type ParamFinder func(string) interface{}
 
+// SourceFinder is a ParamFinder which is also told the Source a field asks for.
+type SourceFinder func(src Source, name string) interface{}
+
+// UnmarshalParams fills the fields of target with values returned by finder.
+// Source tags only rename fields here since finder knows no sources.
+// Values which fail to convert are skipped.
 func UnmarshalParams(target interface{}, finder ParamFinder) error {
+	return unmarshalParamsFinder(target, finder, false)
+}
+
+// UnmarshalParamsStrict is like UnmarshalParams, but returns BindErrors
+// listing every value which failed to convert.
+func UnmarshalParamsStrict(target interface{}, finder ParamFinder) error {
+	return unmarshalParamsFinder(target, finder, true)
+}
+
+func unmarshalParamsFinder(target interface{}, finder ParamFinder, strict bool) error {
+	if finder == nil {
+		return fmt.Errorf("a finder func required.")
+	}
+	return UnmarshalParamsFrom(target, func(src Source, name string) interface{} {
+		return finder(name)
+	}, strict)
+}
+
+// UnmarshalParamsFrom fills the fields of target with values returned by
+// finder. If strict, values which fail to convert are returned as BindErrors.
+func UnmarshalParamsFrom(target interface{}, finder SourceFinder, strict bool) error {
 	if target == nil {
 		return fmt.Errorf("nil input.")
 	}
 	tv := reflect.ValueOf(target)
-	return unmarshalParams(tv, finder)
+	return unmarshalParams(tv, finder, strict)
 }
 
-func unmarshalParams(tv reflect.Value, finder ParamFinder) error {
+func unmarshalParams(tv reflect.Value, finder SourceFinder, strict bool) error {
 	if finder == nil {
 		return fmt.Errorf("a finder func required.")
 	}
@@ -98,84 +142,23 @@ func unmarshalParams(tv reflect.Value, finder ParamFinder) error {
 		if tvType.Elem().Kind() != reflect.Struct {
 			return fmt.Errorf("target must be a struct or *struct.")
 		}
-		return unmarshalParams(tv.Elem(), finder)
+		return unmarshalParams(tv.Elem(), finder, strict)
 	}
 	if tvType.Kind() != reflect.Struct {
 		return fmt.Errorf("target must be a struct or *struct.")
 	}
-	for i := 0; i < tvType.NumField(); i++ {
-		f := tvType.Field(i)
-		varName := f.Name
-		tagReq := f.Tag.Get("req")
-		tagJson := f.Tag.Get("json")
-		if len(tagReq) > 0 {
-			varName = tagReq
-		} else if len(tagJson) > 0 {
-			varName = tagJson
-		}
-
-		sv := finder(varName)
-		if sv == nil {
-			continue
-		}
-		sVal := reflect.ValueOf(sv)
-		sValKind := sVal.Type().Kind()
-		val := ""
-		if sValKind == reflect.String {
-			val = sv.(string)
-		}
-
-		fv := tv.FieldByName(f.Name)
-		if !fv.CanSet() {
-			continue
-		}
-		switch fv.Type().Kind() {
-		case reflect.String:
-			if sValKind == reflect.String {
-				fv.Set(sVal)
-			}
-			break
-		case reflect.Float32:
-			fallthrough
-		case reflect.Float64:
-			if sVal.Type().AssignableTo(fv.Type()) {
-				fv.Set(sVal)
-				break
-			} else if sVal.Type().ConvertibleTo(fv.Type()) {
-				vtmp := sVal.Convert(fv.Type())
-				fv.Set(vtmp)
-				break
-			}
-			if flt, err := strconv.ParseFloat(val, 64); err == nil {
-				iv := reflect.ValueOf(flt)
-				if iv.Type().AssignableTo(fv.Type()) {
-					fv.Set(iv)
-				} else if iv.Type().ConvertibleTo(fv.Type()) {
-					vtmp := iv.Convert(fv.Type())
-					fv.Set(vtmp)
-				}
-			}
-			break
-		default:
-			if sVal.Type().AssignableTo(fv.Type()) {
-				fv.Set(sVal)
-				break
-			} else if sVal.Type().ConvertibleTo(fv.Type()) {
-				vtmp := sVal.Convert(fv.Type())
-				fv.Set(vtmp)
-				break
-			}
-			if intVal, err := strconv.Atoi(val); err == nil {
-				iv := reflect.ValueOf(intVal)
-				if iv.Type().AssignableTo(fv.Type()) {
-					fv.Set(iv)
-				} else if iv.Type().ConvertibleTo(fv.Type()) {
-					vtmp := iv.Convert(fv.Type())
-					fv.Set(vtmp)
-				}
-			}
-			break
-		}
+	plan := paramsPlanOf(tvType)
+	if plan.err != nil {
+		return plan.err
+	}
+	if !strict {
+		plan.apply(tv, finder, nil)
+		return nil
+	}
+	errs := BindErrors(nil)
+	plan.apply(tv, finder, &errs)
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
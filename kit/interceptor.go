@@ -0,0 +1,163 @@
+package kit
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+)
+
+// Call is one invocation of a bound func, as seen by interceptors.
+type Call struct {
+	Writer  http.ResponseWriter
+	Request *http.Request   // may be replaced by BeforeBind hooks, e.g. to add context values.
+	Func    reflect.Type    // the signature of the bound func.
+	Args    []reflect.Value // the resolved arguments, from AfterBind on.
+	Results []reflect.Value // the return values, in AfterCall. Hooks may replace them by values of the same types.
+	Err     error           // the error returned by the func, in AfterCall. Hooks may replace it.
+
+	// In AfterResponse, Err is the error answered, if any, and Phase is
+	// where it came from. Stats describes the response.
+	Phase Phase
+	Stats ResponseStats
+}
+
+// recordErrors returns render, recording the errors it renders in c.
+func (c *Call) recordErrors(render ErrorRenderer) ErrorRenderer {
+	return func(w http.ResponseWriter, req *http.Request, err error, phase Phase) {
+		c.Err, c.Phase = err, phase
+		render(w, req, err, phase)
+	}
+}
+
+// Interceptor hooks into the steps of a bound func. Any hook may be nil.
+//
+// A hook stops the call either by returning an error, which is rendered
+// by the error renderer, or by writing a response itself.
+type Interceptor struct {
+	BeforeBind func(c *Call) error // before arguments are resolved.
+	AfterBind  func(c *Call) error // before the func is called.
+	AfterCall  func(c *Call) error // before the results are written.
+
+	// AfterResponse is called once the response is done, whichever way,
+	// in the same order as AfterCall hooks. It can't change the response.
+	AfterResponse func(c *Call)
+}
+
+type hookStep int
+
+const (
+	stepBeforeBind hookStep = iota
+	stepAfterBind
+	stepAfterCall
+)
+
+func (ic *Interceptor) hook(step hookStep) func(*Call) error {
+	switch step {
+	case stepBeforeBind:
+		return ic.BeforeBind
+	case stepAfterBind:
+		return ic.AfterBind
+	}
+	return ic.AfterCall
+}
+
+var (
+	globalMu           sync.RWMutex
+	globalInterceptors []Interceptor
+)
+
+// Use adds interceptors which run for every bound func, before the
+// interceptors of groups and handlers.
+func Use(ics ...Interceptor) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalInterceptors = append(globalInterceptors, ics...)
+}
+
+// WithInterceptors adds interceptors to a bound func, or to a Binder.
+//
+// BeforeBind and AfterBind hooks run in the order interceptors are added;
+// AfterCall hooks run in the reverse order, so the first interceptor
+// wraps the others.
+func WithInterceptors(ics ...Interceptor) Option {
+	return func(o *bindOptions) {
+		o.interceptors = append(o.interceptors, ics...)
+	}
+}
+
+// allInterceptors returns the global interceptors followed by those of the
+// bound func.
+func (o *bindOptions) allInterceptors() []Interceptor {
+	globalMu.RLock()
+	global := globalInterceptors
+	globalMu.RUnlock()
+	if len(global) == 0 {
+		return o.interceptors
+	}
+	ics := make([]Interceptor, 0, len(global)+len(o.interceptors))
+	ics = append(ics, global...)
+	return append(ics, o.interceptors...)
+}
+
+// intercept runs the hooks of step. It reports false if a hook stopped
+// the call, after rendering its error if any.
+func intercept(ics []Interceptor, step hookStep, c *Call, w *monitoredWriter, render ErrorRenderer, phase Phase) bool {
+	for i := range ics {
+		ic := &ics[i]
+		if step == stepAfterCall {
+			ic = &ics[len(ics)-1-i]
+		}
+		hook := ic.hook(step)
+		if hook == nil {
+			continue
+		}
+		if err := hook(c); err != nil {
+			if !w.headerWrote.Load() {
+				render(w, c.Request, err, phase)
+			}
+			return false
+		}
+		if w.headerWrote.Load() || w.hijacked.Load() {
+			return false
+		}
+	}
+	return true
+}
+
+// afterResponse runs the AfterResponse hooks.
+func afterResponse(ics []Interceptor, c *Call, w *monitoredWriter) {
+	c.Stats = w.Stats()
+	for i := len(ics) - 1; i >= 0; i-- {
+		if hook := ics[i].AfterResponse; hook != nil {
+			hook(c)
+		}
+	}
+}
+
+// Binder binds funcs with a common set of options, such as the
+// interceptors of a group of handlers.
+type Binder struct {
+	opts []Option
+}
+
+// NewBinder returns a Binder applying opts to every func it binds.
+func NewBinder(opts ...Option) *Binder {
+	return &Binder{opts: opts}
+}
+
+// With returns a Binder applying the options of b followed by opts.
+func (b *Binder) With(opts ...Option) *Binder {
+	all := make([]Option, 0, len(b.opts)+len(opts))
+	all = append(all, b.opts...)
+	return &Binder{opts: append(all, opts...)}
+}
+
+// BindFunc is BindFunc with the options of b followed by opts.
+func (b *Binder) BindFunc(fn interface{}, opts ...Option) http.HandlerFunc {
+	return BindFunc(fn, b.With(opts...).opts...)
+}
+
+// F is just shortcut for Binder.BindFunc.
+func (b *Binder) F(fn interface{}, opts ...Option) http.HandlerFunc {
+	return b.BindFunc(fn, opts...)
+}
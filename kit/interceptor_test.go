@@ -0,0 +1,119 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testCtxKey struct{}
+
+func testLogInterceptor(log *[]string, name string) Interceptor {
+	return Interceptor{
+		BeforeBind: func(c *Call) error {
+			*log = append(*log, name+".before")
+			return nil
+		},
+		AfterBind: func(c *Call) error {
+			*log = append(*log, name+".bind")
+			return nil
+		},
+		AfterCall: func(c *Call) error {
+			*log = append(*log, name+".call")
+			return nil
+		},
+	}
+}
+
+func TestInterceptorOrder(t *testing.T) {
+	log := []string{}
+	Use(testLogInterceptor(&log, "global"))
+	defer func() { globalInterceptors = nil }()
+
+	group := NewBinder(WithInterceptors(testLogInterceptor(&log, "group")))
+	h := group.F(func() string {
+		log = append(log, "fn")
+		return "ok"
+	}, WithInterceptors(testLogInterceptor(&log, "handler")))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/", nil))
+	expects := "global.before group.before handler.before " +
+		"global.bind group.bind handler.bind fn " +
+		"handler.call group.call global.call"
+	if got := strings.Join(log, " "); got != expects || w.Body.String() != "ok" {
+		t.Fatalf("unexpected order: %s", got)
+		return
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	called := false
+	fn := func(ctx context.Context, p *testXY) string {
+		called = true
+		return fmt.Sprintf("%d %v", p.X, ctx.Value(testCtxKey{}))
+	}
+
+	cases := []struct {
+		ic     Interceptor
+		status int
+		out    string
+		called bool
+	}{
+		{Interceptor{BeforeBind: func(c *Call) error {
+			return NewError(401, "who are you?")
+		}}, 401, "who are you?", false},
+		{Interceptor{BeforeBind: func(c *Call) error {
+			c.Writer.WriteHeader(http.StatusTeapot)
+			return nil
+		}}, 418, "", false},
+		{Interceptor{AfterBind: func(c *Call) error {
+			if c.Args[1].Interface().(*testXY).X > 5 {
+				return NewError(403, "too big.")
+			}
+			return nil
+		}}, 403, "too big.", false},
+		{Interceptor{BeforeBind: func(c *Call) error {
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), testCtxKey{}, "v"))
+			return nil
+		}}, 200, "7 v", true},
+		{Interceptor{AfterCall: func(c *Call) error {
+			c.Results[0] = reflect.ValueOf(strings.ToUpper(c.Results[0].String()))
+			return nil
+		}}, 200, "7 <NIL>", true},
+		{Interceptor{AfterCall: func(c *Call) error {
+			return NewError(502, "replaced.")
+		}}, 502, "replaced.", true},
+	}
+	for i, c := range cases {
+		called = false
+		w := httptest.NewRecorder()
+		F(fn, WithInterceptors(c.ic))(w, httptest.NewRequest("GET", "/?x=7", nil))
+		if w.Code != c.status || called != c.called {
+			t.Fatalf("case %d: expects %d, called %v. got %d, called %v", i, c.status, c.called, w.Code, called)
+			return
+		}
+		if len(c.out) > 0 && w.Body.String() != c.out {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestInterceptorClearsError(t *testing.T) {
+	fn := func() (string, error) { return "partial", fmt.Errorf("oops") }
+	ic := Interceptor{AfterCall: func(c *Call) error {
+		c.Err = nil
+		return nil
+	}}
+	w := httptest.NewRecorder()
+	F(fn, WithInterceptors(ic))(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 200 || w.Body.String() != "partial" {
+		t.Fatalf("error is not cleared: %d %s", w.Code, w.Body.String())
+		return
+	}
+}
@@ -0,0 +1,106 @@
+package kit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/smallfz/httpkit/metrics"
+)
+
+// httpMetrics are the metrics of bound funcs registered on a registry.
+type httpMetrics struct {
+	requests     *metrics.CounterVec
+	duration     *metrics.HistogramVec
+	inFlight     *metrics.GaugeVec
+	bindFailures *metrics.CounterVec
+}
+
+var (
+	metricsMu  sync.Mutex
+	metricsFor = map[*metrics.Registry]*httpMetrics{}
+)
+
+func httpMetricsOf(reg *metrics.Registry) *httpMetrics {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if m, ok := metricsFor[reg]; ok {
+		return m
+	}
+	m := &httpMetrics{
+		requests: reg.NewCounterVec("http_requests_total",
+			"Requests answered by bound funcs.", "pattern", "method", "status"),
+		duration: reg.NewHistogramVec("http_request_duration_seconds",
+			"Time to answer requests by bound funcs.", metrics.DefBuckets, "pattern", "method", "status"),
+		inFlight: reg.NewGaugeVec("http_requests_in_flight",
+			"Requests being answered by bound funcs.", "pattern", "method"),
+		bindFailures: reg.NewCounterVec("kit_bind_failures_total",
+			"Requests failing before the bound func is called, by phase and kind: validation, params or other.",
+			"pattern", "phase", "kind"),
+	}
+	metricsFor[reg] = m
+	return m
+}
+
+type inFlightKey struct{}
+
+// Metrics returns an interceptor recording the requests of bound funcs on
+// reg, or on metrics.Default if reg is nil:
+//
+//   - http_requests_total and http_request_duration_seconds, labelled by
+//     the ServeMux pattern, the method and the status class, e.g. "2xx".
+//   - http_requests_in_flight, labelled by pattern and method.
+//   - kit_bind_failures_total, labelled by pattern, phase and kind.
+//
+// Serve them with metrics.Handler, e.g. kit.Use(kit.Metrics(nil)).
+func Metrics(reg *metrics.Registry) Interceptor {
+	if reg == nil {
+		reg = metrics.Default
+	}
+	m := httpMetricsOf(reg)
+	return Interceptor{
+		BeforeBind: func(c *Call) error {
+			g := m.inFlight.With(c.Request.Pattern, c.Request.Method)
+			g.Inc()
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), inFlightKey{}, g))
+			return nil
+		},
+		AfterResponse: func(c *Call) {
+			req := c.Request
+			if g, ok := req.Context().Value(inFlightKey{}).(*metrics.Gauge); ok {
+				g.Dec()
+			}
+			status := statusClass(c.Stats)
+			m.requests.With(req.Pattern, req.Method, status).Inc()
+			m.duration.With(req.Pattern, req.Method, status).Observe(c.Stats.Duration.Seconds())
+			if c.Err != nil && (c.Phase == PhaseBind || c.Phase == PhaseExtract) {
+				m.bindFailures.With(req.Pattern, string(c.Phase), failureKind(c.Err)).Inc()
+			}
+		},
+	}
+}
+
+func statusClass(stats ResponseStats) string {
+	if stats.Hijacked {
+		return "hijacked"
+	}
+	status := stats.Status
+	if status == 0 {
+		status = 200
+	}
+	return strconv.Itoa(status/100) + "xx"
+}
+
+func failureKind(err error) string {
+	var ve *ValidationError
+	var bes BindErrors
+	var be *BindError
+	switch {
+	case errors.As(err, &ve):
+		return "validation"
+	case errors.As(err, &bes), errors.As(err, &be):
+		return "params"
+	}
+	return "other"
+}
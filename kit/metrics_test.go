@@ -0,0 +1,75 @@
+package kit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/smallfz/httpkit/metrics"
+	"github.com/smallfz/httpkit/ws"
+)
+
+func TestMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	ws.SetMetrics(reg)
+	defer ws.SetMetrics(nil)
+	r := NewRouter(WithInterceptors(Metrics(reg)))
+	r.Get("/items/{id}", func(p *testUserPath) int { return p.ID })
+	r.Post("/items", func(p *testSearch) string { return "ok" })
+	r.Get("/ws", func(w http.ResponseWriter, req *http.Request) {
+		conn, err := ws.WebSocketHandshake(req, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if f, err := conn.ReadFrame(); err == nil {
+			conn.WriteFrame(f)
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for _, target := range []string{"/items/1", "/items/2", "/items/x"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/items?page=0", nil))
+
+	conn, err := ws.Dial("ws" + strings.TrimPrefix(srv.URL, "http") + "/ws")
+	if err != nil {
+		t.Fatalf("ws.Dial: %v", err)
+		return
+	}
+	conn.WriteFrame(ws.NewTextFrame("hi"))
+	conn.ReadFrame()
+	conn.Close()
+
+	w := httptest.NewRecorder()
+	metrics.Handler(reg).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	out := w.Body.String()
+	for _, line := range []string{
+		`http_requests_total{pattern="GET /items/{id}",method="GET",status="2xx"} 2`,
+		`http_requests_total{pattern="GET /items/{id}",method="GET",status="4xx"} 1`,
+		`http_requests_total{pattern="POST /items",method="POST",status="4xx"} 1`,
+		`http_requests_total{pattern="GET /ws",method="GET",status="hijacked"} 1`,
+		`http_request_duration_seconds_count{pattern="GET /items/{id}",method="GET",status="2xx"} 2`,
+		`http_requests_in_flight{pattern="GET /items/{id}",method="GET"} 0`,
+		`kit_bind_failures_total{pattern="GET /items/{id}",phase="extract",kind="params"} 1`,
+		`kit_bind_failures_total{pattern="POST /items",phase="extract",kind="validation"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("%s is missing:\n%s", line, out)
+			return
+		}
+	}
+	for _, line := range []string{
+		`ws_connections_active{side="client"} 0`,
+		`ws_frames_total{side="client",direction="out",op="text"} 1`,
+		`ws_frames_total{side="server",direction="in",op="text"} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Fatalf("%s is missing:\n%s", line, out)
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package kit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http/httptest"
+	"testing"
+)
+
+type testUpload struct {
+	Title  string                  `req:"title"`
+	Avatar *multipart.FileHeader   `req:"avatar"`
+	Docs   []*multipart.FileHeader `req:"docs"`
+	Note   io.ReadCloser           `req:"note"`
+}
+
+func newTestMultipart(t *testing.T) (*bytes.Buffer, string) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("title", "hello")
+	files := [][2]string{
+		{"avatar", "me.png"}, {"docs", "a.txt"}, {"docs", "b.txt"}, {"note", "note.txt"},
+	}
+	for _, f := range files {
+		fw, err := mw.CreateFormFile(f[0], f[1])
+		if err != nil {
+			t.Fatalf("CreateFormFile: %v", err)
+		}
+		fw.Write([]byte("content of " + f[1]))
+	}
+	mw.Close()
+	return body, mw.FormDataContentType()
+}
+
+func TestMultipartBind(t *testing.T) {
+	h := F(func(u *testUpload) string {
+		note, _ := io.ReadAll(u.Note)
+		return fmt.Sprintf("%s|%s|%d|%s", u.Title, u.Avatar.Filename, len(u.Docs), note)
+	}, WithMultipartMemory(16))
+
+	body, ct := newTestMultipart(t)
+	req := httptest.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", ct)
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Code != 200 {
+		t.Fatalf("expects 200. got %d: %s", w.Code, w.Body.String())
+		return
+	}
+	if w.Body.String() != "hello|me.png|2|content of note.txt" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+	}
+}
+
+func TestMultipartStreaming(t *testing.T) {
+	type query struct {
+		Dir string `req:"dir"`
+	}
+	h := F(func(q *query, mr *multipart.Reader) (string, error) {
+		names := []string{}
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return "", err
+			}
+			names = append(names, part.FormName())
+		}
+		return fmt.Sprintf("%s:%v", q.Dir, names), nil
+	})
+
+	body, ct := newTestMultipart(t)
+	req := httptest.NewRequest("POST", "/upload?dir=tmp", body)
+	req.Header.Set("Content-Type", ct)
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Body.String() != "tmp:[title avatar docs docs note]" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+		return
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("POST", "/upload", nil))
+	if w.Code != 415 {
+		t.Fatalf("expects 415. got %d", w.Code)
+	}
+}
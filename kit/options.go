@@ -0,0 +1,62 @@
+package kit
+
+// Option customizes a single bound func. See BindFunc.
+type Option func(*bindOptions)
+
+type bindOptions struct {
+	renderError ErrorRenderer
+	maxMemory   int64
+	lenient     bool
+
+	interceptors []Interceptor
+	container    *Container
+}
+
+func newBindOptions(opts []Option) *bindOptions {
+	o := &bindOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// errorRenderer returns the renderer for this binder, falling back to
+// DefaultErrorRenderer which is read at request time.
+func (o *bindOptions) errorRenderer() ErrorRenderer {
+	if o.renderError != nil {
+		return o.renderError
+	}
+	return defaultErrorRenderer()
+}
+
+// WithErrorRenderer sets the renderer used for errors of this bound func.
+func WithErrorRenderer(r ErrorRenderer) Option {
+	return func(o *bindOptions) {
+		o.renderError = r
+	}
+}
+
+func (o *bindOptions) multipartMemory() int64 {
+	if o.maxMemory > 0 {
+		return o.maxMemory
+	}
+	return MultipartMemory
+}
+
+// WithMultipartMemory sets the number of bytes of a multipart/form-data
+// body kept in memory for this bound func. See MultipartMemory.
+func WithMultipartMemory(n int64) Option {
+	return func(o *bindOptions) {
+		o.maxMemory = n
+	}
+}
+
+// WithLenientParams makes the bound func skip params which fail to
+// convert, instead of answering 400 with BindErrors.
+func WithLenientParams() Option {
+	return func(o *bindOptions) {
+		o.lenient = true
+	}
+}
@@ -2,8 +2,10 @@ package kit
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 	"reflect"
+	"runtime/debug"
 )
 
 type errEmpty struct {
@@ -14,120 +16,141 @@ type errEmpty struct {
 var typError = reflect.TypeOf(errEmpty{}).Field(0).Type
 
 // F is just shortcut for BindFunc.
-func F(fn interface{}) http.HandlerFunc {
-	return BindFunc(fn)
+func F(fn interface{}, opts ...Option) http.HandlerFunc {
+	return BindFunc(fn, opts...)
 }
 
 // BindFunc makes any giving function to a http.HandlerFunc.
-func BindFunc(fn interface{}) http.HandlerFunc {
+//
+// The signature of fn is analysed once here rather than on every request,
+// so BindFunc panics if fn is not a bindable func.
+func BindFunc(fn interface{}, opts ...Option) http.HandlerFunc {
+	return bindFunc("BindFunc", fn, nil, opts)
+}
+
+// invoker calls a bound func with its resolved arguments.
+type invoker func(args []reflect.Value) []reflect.Value
+
+// bindFunc is BindFunc calling fn through invoke, or by reflection if
+// invoke is nil. name is the API reported when fn can't be bound.
+func bindFunc(name string, fn interface{}, invoke invoker, opts []Option) http.HandlerFunc {
+	o := newBindOptions(opts)
+	plan, err := compileFunc(fn, o.providers())
+	if err != nil {
+		panic(fmt.Sprintf("kit.%s: %v", name, err))
+	}
+	if invoke == nil {
+		invoke = plan.fn.Call
+	}
 	return func(wBase http.ResponseWriter, req *http.Request) {
 		w := newMonitoredWriter(wBase)
+		render := o.errorRenderer()
 
-		typ := reflect.TypeOf(fn)
-		if typ.Kind() != reflect.Func {
-			simple(w, 400, "invalid binding func.")
-			return
+		req, tr := startTrace(req)
+		if tr != nil {
+			render = tr.recordErrors(render)
+			defer tr.end(w)
+			tr.enter("bind")
 		}
 
-		numIn := typ.NumIn()
-
-		args := make([]reflect.Value, numIn)
-		if numIn > 0 {
-			typHttpReq := reflect.TypeOf(req)
-			typCtx := reflect.TypeOf(req.Context())
-			typWriter := reflect.TypeOf(w)
-			extractor := newValueExtractor(req)
-			for i, _ := range args {
-				typArg := typ.In(i)
-				switch typArg {
-				case typHttpReq:
-					args[i] = reflect.ValueOf(req)
-					continue
-				case typWriter:
-					args[i] = reflect.ValueOf(w)
-					continue
-				}
-				if typArg.Kind() == reflect.Interface {
-					if typWriter.Implements(typArg) {
-						args[i] = reflect.ValueOf(w)
-						continue
-					}
-					if typCtx.Implements(typArg) {
-						args[i] = reflect.ValueOf(req.Context())
-						continue
-					}
-				}
-				isPtr := typArg.Kind() == reflect.Ptr
-				if isPtr {
-					typArg = typArg.Elem()
-				}
-				bindable := isPtr && reflect.PointerTo(typArg).Implements(typBindable)
-				bindable = bindable || (!isPtr && typArg.Implements(typBindable))
-				if bindable {
-					arg := reflect.New(typArg)
-					var bind reflect.Value
-					if isPtr {
-						bind = arg.MethodByName("Bind")
-					} else {
-						bind = arg.Elem().MethodByName("Bind")
-					}
-					retVals := bind.Call([]reflect.Value{
-						reflect.ValueOf(w),
-						reflect.ValueOf(req),
-					})
-					for _, v := range retVals {
-						if err, ok := ValueToError(v); ok {
-							simple(w, 419, fmt.Sprintf("%v", err))
-							return
-						}
-					}
-					if isPtr {
-						args[i] = arg
-					} else {
-						args[i] = arg.Elem()
-					}
-					continue
-				}
-				arg, err := extractor.newValueByType(typArg)
+		var call *Call
+		ics := o.allInterceptors()
+		if len(ics) > 0 {
+			call = &Call{Writer: w, Request: req, Func: plan.typ}
+			render = call.recordErrors(render)
+			defer afterResponse(ics, call, w)
+		}
+
+		phase := PhaseBind
+		defer func() {
+			if r := recover(); r != nil {
+				recovered(r, w, req, phase, render)
+			}
+		}()
+
+		if call != nil {
+			if !intercept(ics, stepBeforeBind, call, w, render, PhaseBind) {
+				return
+			}
+			req = call.Request
+		}
+
+		args := make([]reflect.Value, len(plan.args))
+		if len(args) > 0 {
+			extractor := newValueExtractor(req, o, plan.streaming)
+			defer extractor.close()
+			var sc *scope
+			if plan.provided {
+				sc = newScope(o.providers(), w, req)
+				defer sc.close()
+			}
+			for i := range args {
+				a := &plan.args[i]
+				phase = a.phase()
+				arg, err := a.resolve(w, req, extractor, sc)
 				if err != nil {
-					simple(w, 419, fmt.Sprintf("%v", err))
+					render(w, req, err, phase)
 					return
 				}
-				if isPtr {
-					args[i] = arg
-				} else {
-					args[i] = arg.Elem()
-				}
+				args[i] = arg
 			}
 		}
 
-		retVals := reflect.ValueOf(fn).Call(args)
-		if len(retVals) == 0 {
-			simple(w, 200, "")
-			return
+		if call != nil {
+			call.Args = args
+			if !intercept(ics, stepAfterBind, call, w, render, PhaseBind) {
+				return
+			}
 		}
-		retVal := retVals[0]
-		if len(retVals) >= 2 {
-			// check if the last is an error.
-			lastVal := retVals[len(retVals)-1]
-			if err, ok := ValueToError(lastVal); ok {
-				w.WriteHeader(419)
-				msg := fmt.Sprintf("%v", err)
-				w.Write([]byte(msg))
+
+		phase = PhaseHandler
+		tr.enter("handler")
+		retVals := invoke(args)
+		res, body, err := collectResults(plan.results, retVals)
+		if call != nil {
+			call.Results, call.Err = retVals, err
+			if !intercept(ics, stepAfterCall, call, w, render, PhaseHandler) {
 				return
 			}
-			// if lastVal.Type().Kind() == reflect.Interface {
-			// 	if !lastVal.IsNil() && lastVal.Type() == typError {
-			// 		w.WriteHeader(419)
-			// 		msg := fmt.Sprintf("%v", lastVal.Interface())
-			// 		w.Write([]byte(msg))
-			// 		return
-			// 	}
-			// }
+			res, body, _ = collectResults(plan.results, call.Results)
+			err = call.Err
+		}
+		if err != nil {
+			render(w, req, err, PhaseHandler)
+			return
 		}
 
+		phase = PhaseRender
+		tr.enter("render")
+		if err := res.checkStatus(); err != nil {
+			render(w, req, err, PhaseRender)
+			return
+		}
 		w.Header().Set("Cache-Control", "no-store")
+		if !body.IsValid() {
+			res.writeHeader(w)
+			return
+		}
+		writeAuto(w, req, res, body, render)
+	}
+}
 
-		WriteAsResponseAuto(w, retVal)
+// recovered handles a panic r of a bound func. It is logged with its stack
+// and answered 500 through render. If the response is already on its way,
+// the connection is aborted with http.ErrAbortHandler instead.
+func recovered(r interface{}, w *monitoredWriter, req *http.Request, phase Phase, render ErrorRenderer) {
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+	slog.Error("kit: panic in bound func:",
+		"method", req.Method,
+		"path", req.URL.Path,
+		"phase", phase,
+		"panic", r,
+		"stack", string(debug.Stack()),
+	)
+	if w.headerWrote.Load() || w.hijacked.Load() {
+		panic(http.ErrAbortHandler)
 	}
+	render(w, req, WrapError(500, fmt.Errorf("panic: %v", r), ""), phase)
 }
@@ -188,3 +188,238 @@ func TestParamsBindCustom(t *testing.T) {
 		}
 	}
 }
+
+type benchSession struct {
+	Uid string
+}
+
+func (s *benchSession) Bind(w http.ResponseWriter, req *http.Request) error {
+	s.Uid = req.Header.Get("x-uid")
+	return nil
+}
+
+func BenchmarkBindFunc(b *testing.B) {
+	h := F(func(p *testPayload, s *benchSession) int {
+		return p.X * p.Y
+	})
+	req := httptest.NewRequest("GET", "/mul?x=8&y=9", nil)
+	req.Header.Set("x-uid", "bench")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkUnmarshalParams(b *testing.B) {
+	qry := url.Values{"x": {"8"}, "y": {"9"}}
+	finder := func(name string) interface{} {
+		return qry.Get(name)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := testPayload{}
+		if err := UnmarshalParams(&p, finder); err != nil {
+			b.Fatalf("UnmarshalParams: %v", err)
+		}
+	}
+}
+
+func TestBindFuncInvalid(t *testing.T) {
+	for _, fn := range []interface{}{nil, "home", func(...string) string { return "" }} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("expects a panic for %T.", fn)
+				}
+			}()
+			F(fn)
+		}()
+	}
+}
+
+type testDeniedSession struct{}
+
+func (s *testDeniedSession) Bind(w http.ResponseWriter, req *http.Request) error {
+	return NewError(401, "login required.")
+}
+
+func TestParamsBindErrorStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/missing", F(func() (string, error) {
+		err := WrapError(404, fmt.Errorf("sql: no rows"), "no such user.")
+		err.Header = http.Header{"X-Reason": {"gone"}}
+		return "", err
+	}))
+	mux.HandleFunc("/plain", F(func() (string, error) {
+		return "", fmt.Errorf("sql: connection refused")
+	}))
+	mux.HandleFunc("/denied", F(func(s *testDeniedSession) string {
+		return "ok"
+	}))
+	svr := httptest.NewServer(mux)
+	defer svr.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/missing", 404, "no such user."},
+		{"/plain", DefaultErrorStatus, http.StatusText(DefaultErrorStatus)},
+		{"/denied", 401, "login required."},
+	}
+	for _, c := range cases {
+		resp, err := svr.Client().Get(svr.URL + c.path)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+			return
+		}
+		dat, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != c.status {
+			t.Fatalf("%s: expects %d. got %d", c.path, c.status, resp.StatusCode)
+			return
+		}
+		if string(dat) != c.body {
+			t.Fatalf("%s: body is not expected: %s", c.path, string(dat))
+			return
+		}
+	}
+
+	resp, err := svr.Client().Get(svr.URL + "/missing")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.Header.Get("X-Reason") != "gone" {
+		t.Fatalf("header from error is missing.")
+	}
+}
+
+type testSourced struct {
+	ID     int    `path:"id"`
+	Tenant string `header:"X-Tenant"`
+	Sid    string `cookie:"sid"`
+	Page   int    `query:"page"`
+	Name   string `form:"name"`
+	Lang   string `path:"lang" query:"lang" header:"Accept-Language"`
+}
+
+func TestParamsBindSources(t *testing.T) {
+	h := F(func(p *testSourced) string {
+		return fmt.Sprintf("%d|%s|%s|%d|%s|%s", p.ID, p.Tenant, p.Sid, p.Page, p.Name, p.Lang)
+	})
+
+	body := bytes.NewBufferString("name=form-name&page=9&id=7")
+	req := httptest.NewRequest("POST", "/users/42?page=2&name=query-name&lang=fr", body)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("Accept-Language", "de")
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "s3cr3t"})
+	req.SetPathValue("id", "42")
+
+	w := httptest.NewRecorder()
+	h(w, req)
+	if w.Body.String() != "42|acme|s3cr3t|2|form-name|fr" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+		return
+	}
+
+	req = httptest.NewRequest("GET", "/users/1", nil)
+	req.Header.Set("Accept-Language", "de")
+	w = httptest.NewRecorder()
+	h(w, req)
+	if w.Body.String() != "0|||0||de" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+	}
+}
+
+type testRepeated struct {
+	Tags   []string          `req:"tag"`
+	IDs    []int             `req:"ids,comma"`
+	Pair   [2]float64        `req:"pair"`
+	Filter map[string]string `req:"filter"`
+	Limits map[string]int    `query:"limit"`
+	First  string            `req:"tag"`
+}
+
+func TestParamsBindRepeated(t *testing.T) {
+	h := F(func(p *testRepeated) string {
+		return fmt.Sprintf("%v|%v|%v|%v|%v|%s", p.Tags, p.IDs, p.Pair, p.Filter, p.Limits, p.First)
+	})
+
+	qry := "tag=a&tag=b&ids=1,2&ids=3&pair=1.5&pair=2" +
+		"&filter[status]=open&filter[owner]=me&limit[users]=10"
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?"+qry, nil))
+	expected := "[a b]|[1 2 3]|[1.5 2]|map[owner:me status:open]|map[users:10]|a"
+	if w.Body.String() != expected {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+	}
+}
+
+func TestUnmarshalParamsValues(t *testing.T) {
+	qry := url.Values{"tag": {"x", "y"}, "ids": {"4,5"}}
+	p := testRepeated{}
+	err := UnmarshalParams(&p, func(name string) interface{} {
+		if vs, ok := qry[name]; ok {
+			return vs
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UnmarshalParams: %v", err)
+		return
+	}
+	if fmt.Sprintf("%v %v %s", p.Tags, p.IDs, p.First) != "[x y] [4 5] x" {
+		t.Fatalf("unexpected result: %v", p)
+	}
+}
+
+type testPaging struct {
+	Page  int `req:"page"`
+	Limit int `req:"limit"`
+}
+
+type testGeo struct {
+	Lat float64 `req:"lat"`
+	Lng float64 `req:"lng"`
+}
+
+type testAddress struct {
+	City string  `req:"city"`
+	Geo  testGeo `req:"geo"`
+}
+
+type testFilters struct {
+	testPaging
+	Address testAddress  `req:"address"`
+	Billing *testAddress `req:"billing"`
+	Parent  *testFilters `req:"parent"`
+}
+
+func TestParamsBindNested(t *testing.T) {
+	h := F(func(p *testFilters) string {
+		billing := "<nil>"
+		if p.Billing != nil {
+			billing = p.Billing.City
+		}
+		return fmt.Sprintf("%d|%d|%s|%v|%s", p.Page, p.Limit, p.Address.City, p.Address.Geo.Lat, billing)
+	})
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?page=2&limit=5&address.city=Oslo&address[geo][lat]=59.9", nil))
+	if w.Body.String() != "2|5|Oslo|59.9|<nil>" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+		return
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?billing[city]=Bergen", nil))
+	if w.Body.String() != "0|0||0|Bergen" {
+		t.Fatalf("body is not expected: %s", w.Body.String())
+	}
+}
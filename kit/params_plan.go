@@ -0,0 +1,508 @@
+package kit
+
+import (
+	"encoding"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldSetter assigns a value returned by a ParamFinder to a field.
+type fieldSetter func(fv reflect.Value, sv interface{}) error
+
+// Source tells where a parameter is looked up in a request.
+type Source string
+
+const (
+	SourceAny    Source = ""       // form or query values, uploaded files, then path values.
+	SourcePath   Source = "path"   // path values of the route pattern.
+	SourceQuery  Source = "query"  // the URL query only.
+	SourceForm   Source = "form"   // the form body only, including uploaded files.
+	SourceHeader Source = "header" // request headers.
+	SourceCookie Source = "cookie" // cookies.
+	SourceBody   Source = "body"   // the request body. Only reported in BindError.
+)
+
+// sourceTags are the struct tags selecting an explicit Source, in the
+// order they are looked up when a field has more than one.
+var sourceTags = []Source{SourcePath, SourceQuery, SourceForm, SourceHeader, SourceCookie}
+
+// paramKey is a name in a source.
+type paramKey struct {
+	src  Source
+	name string
+}
+
+type fieldPlan struct {
+	index int
+	name  string
+	keys  []paramKey
+	set   fieldSetter
+	def   interface{} // the default tag value, a string or []string, or nil.
+
+	// nested is set for struct fields, whose fields are looked up with
+	// dotted or bracketed keys like address.city or address[city].
+	// Embedded structs are nested without a prefix.
+	nested *paramsPlan
+	ptr    bool // a *struct, allocated only if one of its keys is present.
+}
+
+// paramsPlan caches everything UnmarshalParams needs to know about a struct type.
+type paramsPlan struct {
+	fields  []fieldPlan
+	readers []fieldPlan // io.ReadCloser fields, bound to uploaded files.
+	err     error       // e.g. a default value which doesn't convert.
+}
+
+var paramsPlans sync.Map // reflect.Type -> *paramsPlan
+
+func paramsPlanOf(typ reflect.Type) *paramsPlan {
+	if p, ok := paramsPlans.Load(typ); ok {
+		return p.(*paramsPlan)
+	}
+	p, _ := paramsPlans.LoadOrStore(typ, compileParams(typ))
+	return p.(*paramsPlan)
+}
+
+func compileParams(typ reflect.Type) *paramsPlan {
+	return compileNested(typ, nil, map[reflect.Type]bool{})
+}
+
+var typTextUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// nestable reports if typ is a struct whose fields are bound one by one,
+// rather than a value converted from a single parameter.
+func nestable(typ reflect.Type) bool {
+	if typ == typFileHeader {
+		return false
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct || typ == typTime {
+		return false
+	}
+	if _, ok := lookupConverter(typ); ok {
+		return false
+	}
+	return !reflect.PointerTo(typ).Implements(typTextUnmarshaler)
+}
+
+// compileNested compiles the fields of typ, prefixing their names with
+// prefix. seen guards against recursive types.
+func compileNested(typ reflect.Type, prefix []string, seen map[reflect.Type]bool) *paramsPlan {
+	plan := &paramsPlan{}
+	seen[typ] = true
+	defer delete(seen, typ)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		embedded := f.Anonymous && !hasNameTag(f)
+		if !f.IsExported() && !(embedded && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		fp := fieldPlan{
+			index: i,
+			name:  paramName(f),
+		}
+		if nestable(f.Type) {
+			elem := f.Type
+			if fp.ptr = elem.Kind() == reflect.Ptr; fp.ptr {
+				elem = elem.Elem()
+			}
+			if seen[elem] {
+				continue
+			}
+			sub := prefix
+			if !embedded {
+				sub = append(append([]string{}, prefix...), fp.name)
+			}
+			fp.nested = compileNested(elem, sub, seen)
+			if fp.nested.err != nil && plan.err == nil {
+				plan.err = fp.nested.err
+			}
+			plan.fields = append(plan.fields, fp)
+			continue
+		}
+		fp.keys = paramKeys(f, prefix)
+		fp.set = setterFor(f.Type, fieldOpts{
+			comma:  hasTagOption(f, "comma"),
+			format: f.Tag.Get("format"),
+		})
+		if def, ok := f.Tag.Lookup("default"); ok {
+			fp.def = defaultValue(f.Type, def)
+			if err := fp.set(reflect.New(f.Type).Elem(), fp.def); err != nil && plan.err == nil {
+				plan.err = fmt.Errorf("%v.%s: invalid default %q: %v", typ, f.Name, def, err)
+			}
+		}
+		plan.fields = append(plan.fields, fp)
+		if f.Type == typReadCloser && len(prefix) == 0 {
+			plan.readers = append(plan.readers, fp)
+		}
+	}
+	return plan
+}
+
+// defaultValue prepares the default tag of a field of typ for its setter.
+// Defaults of slices and arrays are comma separated.
+func defaultValue(typ reflect.Type, def string) interface{} {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array) && typ.Elem().Kind() != reflect.Uint8 {
+		if len(def) == 0 {
+			return []string{}
+		}
+		return strings.Split(def, ",")
+	}
+	return def
+}
+
+// hasNameTag reports if field f is given a name by a req, json or source tag.
+func hasNameTag(f reflect.StructField) bool {
+	tags := append([]Source{"req", "json"}, sourceTags...)
+	for _, src := range tags {
+		if name, _, _ := strings.Cut(f.Tag.Get(string(src)), ","); len(name) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// paramName is the name of field f in requests: its req tag, json tag or field name.
+func paramName(f reflect.StructField) string {
+	varName := f.Name
+	tagReq := f.Tag.Get("req")
+	tagJson := f.Tag.Get("json")
+	if len(tagReq) > 0 {
+		varName = tagReq
+	} else if len(tagJson) > 0 {
+		varName = tagJson
+	}
+	if name, _, _ := strings.Cut(varName, ","); len(name) > 0 {
+		varName = name
+	}
+	return varName
+}
+
+// paramKeys lists where field f is looked up. Explicit source tags like
+// `header:"X-Tenant"` take the place of the req/json name. Names of a
+// nested field are looked up as prefix.name and prefix[name], except for
+// headers and cookies.
+func paramKeys(f reflect.StructField, prefix []string) []paramKey {
+	keys := []paramKey{}
+	add := func(src Source, name string) {
+		if len(prefix) == 0 || src == SourceHeader || src == SourceCookie {
+			keys = append(keys, paramKey{src: src, name: name})
+			return
+		}
+		dotted := strings.Join(prefix, ".") + "." + name
+		bracketed := prefix[0] + "[" + strings.Join(append(prefix[1:len(prefix):len(prefix)], name), "][") + "]"
+		keys = append(keys, paramKey{src: src, name: dotted}, paramKey{src: src, name: bracketed})
+	}
+	for _, src := range sourceTags {
+		tag, ok := f.Tag.Lookup(string(src))
+		if !ok {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if len(name) == 0 {
+			name = paramName(f)
+		}
+		add(src, name)
+	}
+	if len(keys) == 0 {
+		add(SourceAny, paramName(f))
+	}
+	return keys
+}
+
+// hasTagOption reports if a name tag of field f has option opt, like `query:"tag,comma"`.
+func hasTagOption(f reflect.StructField, opt string) bool {
+	tags := append([]Source{"req"}, sourceTags...)
+	for _, src := range tags {
+		tag, ok := f.Tag.Lookup(string(src))
+		if !ok {
+			continue
+		}
+		_, opts, _ := strings.Cut(tag, ",")
+		for _, o := range strings.Split(opts, ",") {
+			if o == opt {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileName is the name uploaded files of the field are looked up with.
+func (f *fieldPlan) fileName() (string, bool) {
+	for _, k := range f.keys {
+		if k.src == SourceAny || k.src == SourceForm {
+			return k.name, true
+		}
+	}
+	return "", false
+}
+
+// apply fills tv and reports if any of its keys was present. Fields none
+// of whose keys are present get their default, if any. Conversion failures
+// are appended to errs, or ignored if errs is nil.
+func (p *paramsPlan) apply(tv reflect.Value, finder SourceFinder, errs *BindErrors) bool {
+	found := false
+	for i := range p.fields {
+		f := &p.fields[i]
+		fv := tv.Field(f.index)
+		if f.nested != nil {
+			if !f.ptr {
+				found = f.nested.apply(fv, finder, errs) || found
+			} else if !fv.IsNil() {
+				found = f.nested.apply(fv.Elem(), finder, errs) || found
+			} else if fv.CanSet() {
+				sub := reflect.New(fv.Type().Elem())
+				if f.nested.apply(sub.Elem(), finder, errs) {
+					fv.Set(sub)
+					found = true
+				}
+			}
+			continue
+		}
+		present := false
+		for _, k := range f.keys {
+			if sv := finder(k.src, k.name); sv != nil {
+				if err := f.set(fv, sv); err != nil && errs != nil {
+					*errs = append(*errs, newBindError(k, fv.Type(), sv, err))
+				}
+				present = true
+				break
+			}
+		}
+		if present {
+			found = true
+		} else if f.def != nil {
+			f.set(fv, f.def)
+		}
+	}
+	return found
+}
+
+var (
+	typFileHeader  = reflect.TypeOf((*multipart.FileHeader)(nil))
+	typFileHeaders = reflect.TypeOf([]*multipart.FileHeader(nil))
+	typReadCloser  = reflect.TypeOf((*io.ReadCloser)(nil)).Elem()
+)
+
+var errNotAssignable = fmt.Errorf("not assignable.")
+
+// fieldOpts are the tag options affecting how a field is set.
+type fieldOpts struct {
+	comma  bool   // split every value of a slice or array by comma.
+	format string // layout of time.Time values.
+}
+
+// setterFor returns the setter of a field of typ.
+func setterFor(typ reflect.Type, opts fieldOpts) fieldSetter {
+	switch typ {
+	case typFileHeader:
+		return setFileHeader
+	case typFileHeaders:
+		return setAssign
+	case typReadCloser:
+		return setReader
+	}
+	if _, ok := lookupConverter(typ); ok {
+		return scalarSetter(typ, opts)
+	}
+	switch typ.Kind() {
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return scalarSetter(typ, opts) // []byte is taken as a single value.
+		}
+		return sliceSetter(typ, opts)
+	case reflect.Array:
+		return arraySetter(typ, opts)
+	case reflect.Map:
+		if typ.Key().Kind() == reflect.String {
+			return mapSetter(typ, opts)
+		}
+	}
+	return scalarSetter(typ, opts)
+}
+
+// assignDirect sets fv to sv if the types are compatible.
+func assignDirect(fv, sVal reflect.Value) bool {
+	if sVal.Type().AssignableTo(fv.Type()) {
+		fv.Set(sVal)
+		return true
+	} else if sVal.Type().ConvertibleTo(fv.Type()) {
+		fv.Set(sVal.Convert(fv.Type()))
+		return true
+	}
+	return false
+}
+
+// stringValues returns sv as a list of strings if it is a string or []string.
+func stringValues(sv interface{}) ([]string, bool) {
+	switch v := sv.(type) {
+	case string:
+		return []string{v}, true
+	case []string:
+		return v, true
+	}
+	return nil, false
+}
+
+func splitComma(values []string) []string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); len(item) > 0 {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// scalarSetter converts the first of the values given to a field of typ.
+// Values which aren't strings, returned by custom finders, are assigned
+// directly if their type allows. Empty strings are skipped for anything
+// but strings.
+func scalarSetter(typ reflect.Type, opts fieldOpts) fieldSetter {
+	conv := converterFor(typ, opts.format)
+	isString := typ.Kind() == reflect.String
+	return func(fv reflect.Value, sv interface{}) error {
+		if values, ok := sv.([]string); ok {
+			if len(values) == 0 {
+				return nil
+			}
+			sv = values[0]
+		}
+		if s, ok := sv.(string); ok {
+			if len(s) == 0 && !isString {
+				return nil
+			}
+			return conv(fv, s)
+		}
+		if !isString && assignDirect(fv, reflect.ValueOf(sv)) {
+			return nil
+		}
+		return errNotAssignable
+	}
+}
+
+func sliceSetter(typ reflect.Type, opts fieldOpts) fieldSetter {
+	setElem := setterFor(typ.Elem(), fieldOpts{format: opts.format})
+	return func(fv reflect.Value, sv interface{}) error {
+		values, ok := stringValues(sv)
+		if !ok {
+			if assignDirect(fv, reflect.ValueOf(sv)) {
+				return nil
+			}
+			return errNotAssignable
+		}
+		if opts.comma {
+			values = splitComma(values)
+		}
+		out := reflect.MakeSlice(typ, 0, len(values))
+		for _, v := range values {
+			ev := reflect.New(typ.Elem()).Elem()
+			if err := setElem(ev, v); err != nil {
+				return err
+			}
+			out = reflect.Append(out, ev)
+		}
+		fv.Set(out)
+		return nil
+	}
+}
+
+func arraySetter(typ reflect.Type, opts fieldOpts) fieldSetter {
+	setElem := setterFor(typ.Elem(), fieldOpts{format: opts.format})
+	return func(fv reflect.Value, sv interface{}) error {
+		values, ok := stringValues(sv)
+		if !ok {
+			if assignDirect(fv, reflect.ValueOf(sv)) {
+				return nil
+			}
+			return errNotAssignable
+		}
+		if opts.comma {
+			values = splitComma(values)
+		}
+		if len(values) > typ.Len() {
+			return fmt.Errorf("too many values: %d > %d.", len(values), typ.Len())
+		}
+		out := reflect.New(typ).Elem()
+		for i, v := range values {
+			if err := setElem(out.Index(i), v); err != nil {
+				return err
+			}
+		}
+		fv.Set(out)
+		return nil
+	}
+}
+
+// mapSetter fills map[string]T from a map[string][]string, which the
+// finder builds from bracket notation like filter[status]=open.
+func mapSetter(typ reflect.Type, opts fieldOpts) fieldSetter {
+	setElem := setterFor(typ.Elem(), opts)
+	return func(fv reflect.Value, sv interface{}) error {
+		var values map[string][]string
+		switch v := sv.(type) {
+		case map[string][]string:
+			values = v
+		case url.Values:
+			values = v
+		default:
+			if assignDirect(fv, reflect.ValueOf(sv)) {
+				return nil
+			}
+			if _, isStr := stringValues(sv); isStr {
+				return nil // a plain value, not a map.
+			}
+			return errNotAssignable
+		}
+		out := reflect.MakeMapWithSize(typ, len(values))
+		for k, vs := range values {
+			ev := reflect.New(typ.Elem()).Elem()
+			if err := setElem(ev, vs); err != nil {
+				return err
+			}
+			out.SetMapIndex(reflect.ValueOf(k).Convert(typ.Key()), ev)
+		}
+		fv.Set(out)
+		return nil
+	}
+}
+
+func setFileHeader(fv reflect.Value, sv interface{}) error {
+	switch v := sv.(type) {
+	case *multipart.FileHeader:
+		fv.Set(reflect.ValueOf(v))
+	case []*multipart.FileHeader:
+		if len(v) > 0 {
+			fv.Set(reflect.ValueOf(v[0]))
+		}
+	}
+	return nil
+}
+
+// setReader leaves uploaded files to valueExtractor.openFiles.
+func setReader(fv reflect.Value, sv interface{}) error {
+	if _, ok := sv.([]*multipart.FileHeader); ok {
+		return nil
+	}
+	return setAssign(fv, sv)
+}
+
+func setAssign(fv reflect.Value, sv interface{}) error {
+	if assignDirect(fv, reflect.ValueOf(sv)) {
+		return nil
+	}
+	return errNotAssignable
+}
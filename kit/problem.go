@@ -0,0 +1,109 @@
+package kit
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Problem is a RFC 7807 problem details object.
+// It is also an error, so a bound func may return it directly.
+type Problem struct {
+	Type     string `json:"type,omitempty"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	// Extensions are written as additional members of the object.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+var _ StatusError = (*Problem)(nil)
+
+// ProblemExtender may be implemented by errors to add members to the
+// problem details written by ProblemRenderer.
+type ProblemExtender interface {
+	ProblemExtensions() map[string]interface{}
+}
+
+func (p *Problem) Error() string {
+	if len(p.Detail) > 0 {
+		return p.Detail
+	}
+	return p.Title
+}
+
+func (p *Problem) StatusCode() int {
+	return p.Status
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type problem Problem
+	dat, err := json.Marshal((*problem)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return dat, err
+	}
+	members := map[string]interface{}{}
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(dat, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// NewProblem builds the problem details for err.
+func NewProblem(req *http.Request, err error, phase Phase) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		cp := *p
+		p = &cp
+		if p.Status <= 0 {
+			p.Status = phase.DefaultStatus()
+		}
+		if len(p.Title) == 0 {
+			p.Title = http.StatusText(p.Status)
+		}
+	} else {
+		status := ErrorStatus(err, phase.DefaultStatus())
+		p = &Problem{
+			Type:   "about:blank",
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: errorMessage(req, err, status),
+		}
+		if p.Detail == p.Title {
+			p.Detail = ""
+		}
+	}
+	if len(p.Instance) == 0 && req != nil {
+		p.Instance = req.URL.Path
+	}
+	var ext ProblemExtender
+	if errors.As(err, &ext) {
+		if p.Extensions == nil {
+			p.Extensions = map[string]interface{}{}
+		}
+		for k, v := range ext.ProblemExtensions() {
+			p.Extensions[k] = v
+		}
+	}
+	return p
+}
+
+// ProblemRenderer is an ErrorRenderer writing application/problem+json.
+func ProblemRenderer(w http.ResponseWriter, req *http.Request, err error, phase Phase) {
+	p := NewProblem(req, err, phase)
+	dat, errM := json.Marshal(p)
+	if errM != nil {
+		TextErrorRenderer(w, req, err, phase)
+		return
+	}
+	writeErrorHeaders(w, err)
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(p.Status)
+	w.Write(dat)
+}
@@ -0,0 +1,321 @@
+package kit
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Container holds providers of argument types. When a bound func has an
+// argument of a provided type, the provider is called to produce it.
+//
+// A provider is a func returning the provided type, optionally followed
+// by a cleanup func() and an error:
+//
+//	func(deps...) T
+//	func(deps...) (T, error)
+//	func(deps...) (T, func())
+//	func(deps...) (T, func(), error)
+//
+// Its arguments are other provided types, *http.Request, context.Context
+// or http.ResponseWriter. A provider failing with an error which isn't a
+// StatusError answers 500. Providers are called at most once per request,
+// and their cleanups are called in reverse order once the response is
+// done. A singleton provider is called once, on first use; its cleanup is
+// called by Close.
+//
+// Providers must be registered before the funcs using them are bound.
+type Container struct {
+	mu        sync.RWMutex
+	providers map[reflect.Type]*provider
+}
+
+type provider struct {
+	fn        reflect.Value
+	out       reflect.Type
+	deps      []reflect.Type
+	cleanupAt int // index of the cleanup result, or -1.
+	errAt     int // index of the error result, or -1.
+	singleton bool
+
+	mu      sync.Mutex
+	done    bool
+	value   reflect.Value
+	cleanup func()
+}
+
+var (
+	typCleanup        = reflect.TypeOf(func() {})
+	typResponseWriter = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+)
+
+// DefaultContainer is the Container of bound funcs which aren't given
+// their own by WithContainer.
+var DefaultContainer = NewContainer()
+
+// NewContainer returns an empty Container.
+func NewContainer() *Container {
+	return &Container{providers: map[reflect.Type]*provider{}}
+}
+
+// Provide registers fn as the provider of its first result type on
+// DefaultContainer.
+func Provide(fn interface{}) error {
+	return DefaultContainer.Provide(fn)
+}
+
+// ProvideSingleton registers fn as a singleton provider on
+// DefaultContainer.
+func ProvideSingleton(fn interface{}) error {
+	return DefaultContainer.ProvideSingleton(fn)
+}
+
+// WithContainer makes the bound func resolve provided arguments from c
+// instead of DefaultContainer.
+func WithContainer(c *Container) Option {
+	return func(o *bindOptions) {
+		o.container = c
+	}
+}
+
+func (o *bindOptions) providers() *Container {
+	if o.container != nil {
+		return o.container
+	}
+	return DefaultContainer
+}
+
+// Provide registers fn as the provider of its first result type, called
+// once per request. It fails if the type is already provided, or if fn
+// would depend on itself through other providers.
+func (c *Container) Provide(fn interface{}) error {
+	return c.register(fn, false)
+}
+
+// ProvideSingleton is like Provide, but fn is called only once. It may
+// depend on other singletons only.
+func (c *Container) ProvideSingleton(fn interface{}) error {
+	return c.register(fn, true)
+}
+
+// ProvideValue registers v as a singleton of its own type.
+func (c *Container) ProvideValue(v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("nil value.")
+	}
+	val := reflect.ValueOf(v)
+	fn := reflect.MakeFunc(reflect.FuncOf(nil, []reflect.Type{val.Type()}, false),
+		func([]reflect.Value) []reflect.Value { return []reflect.Value{val} })
+	return c.register(fn.Interface(), true)
+}
+
+func newProvider(fn interface{}, singleton bool) (*provider, error) {
+	if fn == nil {
+		return nil, fmt.Errorf("nil provider.")
+	}
+	typ := reflect.TypeOf(fn)
+	if typ.Kind() != reflect.Func || typ.IsVariadic() {
+		return nil, fmt.Errorf("invalid provider: %v is not a non-variadic func.", typ)
+	}
+	p := &provider{
+		fn:        reflect.ValueOf(fn),
+		cleanupAt: -1,
+		errAt:     -1,
+		singleton: singleton,
+	}
+	n := typ.NumOut()
+	if n > 0 && typ.Out(n-1) == typError {
+		p.errAt = n - 1
+		n--
+	}
+	if n == 2 && typ.Out(1) == typCleanup {
+		p.cleanupAt = 1
+		n--
+	}
+	if n != 1 {
+		return nil, fmt.Errorf("invalid provider: %v must return T, (T, error), (T, func()) or (T, func(), error).", typ)
+	}
+	p.out = typ.Out(0)
+	for i := 0; i < typ.NumIn(); i++ {
+		p.deps = append(p.deps, typ.In(i))
+	}
+	return p, nil
+}
+
+func (c *Container) register(fn interface{}, singleton bool) error {
+	p, err := newProvider(fn, singleton)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.providers[p.out]; ok {
+		return fmt.Errorf("a provider of %v is already registered.", p.out)
+	}
+	if path := c.cycle(p.out, p.deps, []reflect.Type{p.out}); path != nil {
+		names := make([]string, len(path))
+		for i, t := range path {
+			names[i] = t.String()
+		}
+		return fmt.Errorf("dependency cycle: %s.", strings.Join(names, " -> "))
+	}
+	c.providers[p.out] = p
+	return nil
+}
+
+// cycle returns the path from target back to itself through deps, or nil.
+func (c *Container) cycle(target reflect.Type, deps []reflect.Type, path []reflect.Type) []reflect.Type {
+	for _, dep := range deps {
+		next := append(path[:len(path):len(path)], dep)
+		if dep == target {
+			return next
+		}
+		if p, ok := c.providers[dep]; ok {
+			if found := c.cycle(target, p.deps, next); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Container) lookup(typ reflect.Type) *provider {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.providers[typ]
+}
+
+func isBuiltinDep(typ reflect.Type) bool {
+	return typ == typHttpReq || typ == typContext || typ == typResponseWriter
+}
+
+// check makes sure every dependency of the provider of typ is provided,
+// and that singletons depend on singletons only.
+func (c *Container) check(typ reflect.Type) error {
+	p := c.lookup(typ)
+	if p == nil {
+		return fmt.Errorf("no provider of %v.", typ)
+	}
+	for _, dep := range p.deps {
+		if isBuiltinDep(dep) {
+			if p.singleton {
+				return fmt.Errorf("singleton %v depends on the request.", typ)
+			}
+			continue
+		}
+		if err := c.check(dep); err != nil {
+			return err
+		}
+		if p.singleton && !c.lookup(dep).singleton {
+			return fmt.Errorf("singleton %v depends on %v, which is not a singleton.", typ, dep)
+		}
+	}
+	return nil
+}
+
+// Close calls the cleanups of the singletons created so far.
+func (c *Container) Close() {
+	c.mu.RLock()
+	providers := make([]*provider, 0, len(c.providers))
+	for _, p := range c.providers {
+		providers = append(providers, p)
+	}
+	c.mu.RUnlock()
+	for _, p := range providers {
+		p.mu.Lock()
+		if p.cleanup != nil {
+			p.cleanup()
+		}
+		p.done, p.value, p.cleanup = false, reflect.Value{}, nil
+		p.mu.Unlock()
+	}
+}
+
+// scope resolves provided values for a single request.
+type scope struct {
+	c        *Container
+	w        http.ResponseWriter
+	req      *http.Request
+	values   map[reflect.Type]reflect.Value
+	cleanups []func()
+}
+
+func newScope(c *Container, w http.ResponseWriter, req *http.Request) *scope {
+	return &scope{c: c, w: w, req: req, values: map[reflect.Type]reflect.Value{}}
+}
+
+// close calls the cleanups of the request in reverse order.
+func (s *scope) close() {
+	for i := len(s.cleanups) - 1; i >= 0; i-- {
+		s.cleanups[i]()
+	}
+	s.cleanups = nil
+}
+
+func (s *scope) get(typ reflect.Type) (reflect.Value, error) {
+	switch typ {
+	case typHttpReq:
+		return reflect.ValueOf(s.req), nil
+	case typContext:
+		return reflect.ValueOf(s.req.Context()), nil
+	case typResponseWriter:
+		return reflect.ValueOf(&s.w).Elem(), nil
+	}
+	if v, ok := s.values[typ]; ok {
+		return v, nil
+	}
+	p := s.c.lookup(typ)
+	if p == nil {
+		return reflect.Value{}, fmt.Errorf("no provider of %v.", typ)
+	}
+	if p.singleton {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.done {
+			return p.value, nil
+		}
+	}
+	v, cleanup, err := s.call(p)
+	if err != nil {
+		return v, err
+	}
+	if p.singleton {
+		p.done, p.value, p.cleanup = true, v, cleanup
+		return v, nil
+	}
+	s.values[typ] = v
+	if cleanup != nil {
+		s.cleanups = append(s.cleanups, cleanup)
+	}
+	return v, nil
+}
+
+func (s *scope) call(p *provider) (reflect.Value, func(), error) {
+	args := make([]reflect.Value, len(p.deps))
+	for i, dep := range p.deps {
+		v, err := s.get(dep)
+		if err != nil {
+			return v, nil, err
+		}
+		args[i] = v
+	}
+	outs := p.fn.Call(args)
+	var cleanup func()
+	if p.cleanupAt >= 0 {
+		cleanup, _ = outs[p.cleanupAt].Interface().(func())
+	}
+	if p.errAt >= 0 {
+		if err, ok := ValueToError(outs[p.errAt]); ok {
+			if cleanup != nil {
+				cleanup()
+			}
+			if ErrorStatus(err, 0) == 0 {
+				err = WrapError(http.StatusInternalServerError, err, "")
+			}
+			return outs[0], nil, err
+		}
+	}
+	return outs[0], cleanup, nil
+}
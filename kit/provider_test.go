@@ -0,0 +1,142 @@
+package kit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testDB struct{ name string }
+
+type testTx struct {
+	db   *testDB
+	user string
+}
+
+type testRepo struct{ tx *testTx }
+
+type testCycleA struct{}
+
+type testCycleB struct{}
+
+func TestProviders(t *testing.T) {
+	log := []string{}
+	c := NewContainer()
+	if err := c.ProvideValue(&testDB{name: "main"}); err != nil {
+		t.Fatalf("ProvideValue: %v", err)
+		return
+	}
+	err := c.Provide(func(db *testDB, req *http.Request) (*testTx, func(), error) {
+		user := req.Header.Get("X-User")
+		if len(user) == 0 {
+			return nil, nil, NewError(401, "no user.")
+		}
+		if user == "broken" {
+			return nil, nil, fmt.Errorf("connection refused")
+		}
+		log = append(log, "begin")
+		return &testTx{db: db, user: user}, func() { log = append(log, "end") }, nil
+	})
+	if err != nil {
+		t.Fatalf("Provide: %v", err)
+		return
+	}
+	if err := c.Provide(func(tx *testTx) *testRepo { return &testRepo{tx: tx} }); err != nil {
+		t.Fatalf("Provide: %v", err)
+		return
+	}
+
+	h := F(func(tx *testTx, repo *testRepo) string {
+		log = append(log, "handler")
+		return fmt.Sprintf("%s %s %v", tx.db.name, tx.user, repo.tx == tx)
+	}, WithContainer(c))
+
+	cases := []struct {
+		user   string
+		status int
+		out    string
+		log    string
+	}{
+		{"amy", 200, "main amy true", "begin handler end"},
+		{"", 401, "no user.", ""},
+		{"broken", 500, "Internal Server Error", ""},
+	}
+	for i, cs := range cases {
+		log = log[:0]
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-User", cs.user)
+		h(w, req)
+		if w.Code != cs.status || w.Body.String() != cs.out {
+			t.Fatalf("case %d: unexpected response %d: %s", i, w.Code, w.Body.String())
+			return
+		}
+		if got := strings.Join(log, " "); got != cs.log {
+			t.Fatalf("case %d: expects %q. got %q", i, cs.log, got)
+			return
+		}
+	}
+}
+
+func TestProviderRegistration(t *testing.T) {
+	c := NewContainer()
+	if err := c.Provide(func(b testCycleB) testCycleA { return testCycleA{} }); err != nil {
+		t.Fatalf("Provide: %v", err)
+		return
+	}
+	err := c.Provide(func(a testCycleA) testCycleB { return testCycleB{} })
+	if err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("cycle is not detected: %v", err)
+		return
+	}
+	if err := c.Provide(func() testCycleA { return testCycleA{} }); err == nil {
+		t.Fatalf("duplicated provider is accepted.")
+		return
+	}
+	if err := c.Provide(func() (int, string) { return 0, "" }); err == nil {
+		t.Fatalf("invalid provider is accepted.")
+		return
+	}
+
+	// testCycleB, a dependency of testCycleA, is not provided.
+	invalid := []interface{}{
+		func(a testCycleA) {},
+	}
+	c.ProvideSingleton(func(req *http.Request) *testDB { return nil })
+	invalid = append(invalid, func(db *testDB) {})
+	for i, fn := range invalid {
+		func() {
+			defer func() {
+				if r := recover(); r == nil {
+					t.Fatalf("case %d: BindFunc accepts an unresolvable func.", i)
+				}
+			}()
+			F(fn, WithContainer(c))
+		}()
+	}
+}
+
+func TestProviderSingleton(t *testing.T) {
+	n, closed := 0, 0
+	c := NewContainer()
+	c.ProvideSingleton(func() (*testDB, func()) {
+		n++
+		return &testDB{name: fmt.Sprint(n)}, func() { closed++ }
+	})
+	h := F(func(db *testDB) string { return db.name }, WithContainer(c))
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("GET", "/", nil))
+		if w.Body.String() != "1" {
+			t.Fatalf("singleton is not reused: %s", w.Body.String())
+			return
+		}
+	}
+	c.Close()
+	if n != 1 || closed != 1 {
+		t.Fatalf("expects 1 call and 1 cleanup. got %d and %d", n, closed)
+		return
+	}
+}
@@ -0,0 +1,64 @@
+package kit
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Phase tells where an error came from while serving a bound func.
+type Phase string
+
+const (
+	PhaseRoute   Phase = "route"   // no route matches the request. See Router.
+	PhaseBind    Phase = "bind"    // Bindable.Bind failed.
+	PhaseExtract Phase = "extract" // building a payload from the request failed.
+	PhaseHandler Phase = "handler" // the bound func returned an error.
+	PhaseRender  Phase = "render"  // serializing the result failed.
+)
+
+// DefaultStatus is the status code used for errors of this phase which
+// don't implement StatusError.
+func (p Phase) DefaultStatus() int {
+	switch p {
+	case PhaseRoute:
+		return http.StatusNotFound
+	case PhaseBind, PhaseExtract:
+		return DefaultBindErrorStatus
+	}
+	return DefaultErrorStatus
+}
+
+// ErrorRenderer writes err as the response. req may be nil when the error
+// happened outside of a bound func, e.g. in JSON.
+type ErrorRenderer func(w http.ResponseWriter, req *http.Request, err error, phase Phase)
+
+// DefaultErrorRenderer renders errors of every bound func which isn't given
+// its own renderer by WithErrorRenderer.
+var DefaultErrorRenderer ErrorRenderer = TextErrorRenderer
+
+func defaultErrorRenderer() ErrorRenderer {
+	if DefaultErrorRenderer != nil {
+		return DefaultErrorRenderer
+	}
+	return TextErrorRenderer
+}
+
+func writeErrorHeaders(w http.ResponseWriter, err error) {
+	var hc headersCarrier
+	if errors.As(err, &hc) {
+		for name, values := range hc.Headers() {
+			for _, v := range values {
+				w.Header().Add(name, v)
+			}
+		}
+	}
+}
+
+// TextErrorRenderer writes the public message of err as text/plain. The
+// text of a server error without one is not sent; see StatusError.
+func TextErrorRenderer(w http.ResponseWriter, req *http.Request, err error, phase Phase) {
+	writeErrorHeaders(w, err)
+	w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+	status := ErrorStatus(err, phase.DefaultStatus())
+	simple(w, status, errorMessage(req, err, status))
+}
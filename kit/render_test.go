@@ -0,0 +1,182 @@
+package kit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblemRenderer(t *testing.T) {
+	h := F(func() (string, error) {
+		return "", NewError(409, "already exists.")
+	}, WithErrorRenderer(ProblemRenderer))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("POST", "/users", nil))
+
+	if w.Code != 409 {
+		t.Fatalf("expects 409. got %d", w.Code)
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("unexpected content type: %s", ct)
+		return
+	}
+	p := map[string]interface{}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+		return
+	}
+	if p["title"] != "Conflict" || p["detail"] != "already exists." || p["instance"] != "/users" {
+		t.Fatalf("unexpected problem: %s", w.Body.String())
+	}
+}
+
+func TestErrorRendererPhases(t *testing.T) {
+	phases := []Phase{}
+	render := func(w http.ResponseWriter, req *http.Request, err error, phase Phase) {
+		phases = append(phases, phase)
+		TextErrorRenderer(w, req, err, phase)
+	}
+
+	cases := []struct {
+		fn     interface{}
+		status int
+		phase  Phase
+	}{
+		{func(s *testDeniedSession) string { return "" }, 401, PhaseBind},
+		{func() (int, error) { return 0, fmt.Errorf("x") }, 500, PhaseHandler},
+		{func() func() { return func() {} }, 500, PhaseRender},
+		{func() string { panic("oops") }, 500, PhaseHandler},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		F(c.fn, WithErrorRenderer(render))(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d", i, c.status, w.Code)
+			return
+		}
+		if len(phases) != i+1 || phases[i] != c.phase {
+			t.Fatalf("case %d: expects phase %s. got %v", i, c.phase, phases)
+			return
+		}
+	}
+}
+
+type testPanicSession struct{}
+
+func (s *testPanicSession) Bind(w http.ResponseWriter, req *http.Request) error {
+	panic("bind exploded")
+}
+
+func TestPanicRecovery(t *testing.T) {
+	buf := &bytes.Buffer{}
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(buf, nil)))
+
+	cases := []struct {
+		fn     interface{}
+		status int
+		log    string
+	}{
+		{func(s *testPanicSession) string { return "" }, 500, "bind exploded"},
+		{func() string { panic("handler exploded") }, 500, "handler exploded"},
+		{func() string {
+			var m map[string]int
+			m["x"] = 1
+			return ""
+		}, 500, "assignment to entry in nil map"},
+	}
+	for i, c := range cases {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		F(c.fn)(w, httptest.NewRequest("PUT", "/boom", nil))
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d", i, c.status, w.Code)
+			return
+		}
+		out := buf.String()
+		for _, s := range []string{c.log, "method=PUT", "path=/boom", "stack=", "TestPanicRecovery"} {
+			if !strings.Contains(out, s) {
+				t.Fatalf("case %d: %q is not logged: %s", i, s, out)
+				return
+			}
+		}
+	}
+}
+
+func TestPanicAfterHeaders(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+
+	for i, fn := range []interface{}{
+		func(w http.ResponseWriter) string {
+			w.WriteHeader(200)
+			panic("too late")
+		},
+		func() string { panic(http.ErrAbortHandler) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != http.ErrAbortHandler {
+					t.Fatalf("case %d: expects http.ErrAbortHandler. got %v", i, r)
+				}
+			}()
+			F(fn)(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+		}()
+	}
+}
+
+func TestJSONHelper(t *testing.T) {
+	w := httptest.NewRecorder()
+	JSON(w, 0, map[string]int{"a": 1})
+	if w.Code != 200 || w.Body.String() != `{"a":1}` {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	w = httptest.NewRecorder()
+	w.WriteHeader(201)
+	JSON(w, 200, []int{1})
+	if w.Code != 201 {
+		t.Fatalf("status written before is changed: %d", w.Code)
+		return
+	}
+
+	w = httptest.NewRecorder()
+	JSON(w, 200, func() {})
+	if w.Code != 500 {
+		t.Fatalf("expects 500 for a marshaling failure. got %d", w.Code)
+		return
+	}
+}
+
+func TestInternalErrorHidden(t *testing.T) {
+	fn := func() (string, error) {
+		return "", fmt.Errorf("sql: connection refused")
+	}
+	for _, render := range []ErrorRenderer{TextErrorRenderer, ProblemRenderer} {
+		w := httptest.NewRecorder()
+		F(fn, WithErrorRenderer(render))(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 500 {
+			t.Fatalf("expects 500. got %d", w.Code)
+			return
+		}
+		if strings.Contains(w.Body.String(), "sql") {
+			t.Fatalf("internal error text is sent: %s", w.Body.String())
+			return
+		}
+	}
+
+	w := httptest.NewRecorder()
+	F(func() error { return WrapError(503, fmt.Errorf("sql: down"), "try later.") })(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != 503 || w.Body.String() != "try later." {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+		return
+	}
+}
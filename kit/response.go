@@ -0,0 +1,167 @@
+package kit
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// Response lets a bound func control the status code, headers and cookies
+// of its answer. Body is written like any other return value.
+//
+//	mux.HandleFunc("POST /users", kit.F(func(u *User) (*kit.Response, error) {
+//	    id, err := createUser(u)
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    return &kit.Response{
+//	        Status: 201,
+//	        Header: http.Header{"Location": {"/users/" + id}},
+//	        Body:   u,
+//	    }, nil
+//	}))
+type Response struct {
+	Status  int
+	Header  http.Header
+	Cookies []*http.Cookie
+	Body    interface{}
+}
+
+var (
+	typResponse    = reflect.TypeOf(Response{})
+	typResponsePtr = reflect.TypeOf((*Response)(nil))
+	typHeader      = reflect.TypeOf(http.Header{})
+	typCookie      = reflect.TypeOf((*http.Cookie)(nil))
+	typCookies     = reflect.TypeOf([]*http.Cookie(nil))
+	typInt         = reflect.TypeOf(0)
+)
+
+type retKind int
+
+const (
+	retBody retKind = iota
+	retStatus
+	retError
+	retResponse
+	retHeader
+	retCookie
+	retCookies
+	retIgnored
+)
+
+// compileResults classifies the results of a bind func:
+//
+//   - a trailing error is the error.
+//   - an int followed by another result is the status code, as in (int, T).
+//   - Response or *Response, http.Header, *http.Cookie and []*http.Cookie
+//     are merged into the answer.
+//   - the first other result is the body. Further ones are ignored.
+func compileResults(typ reflect.Type) []retKind {
+	n := typ.NumOut()
+	kinds := make([]retKind, n)
+	hasBody := false
+	for i := 0; i < n; i++ {
+		t := typ.Out(i)
+		switch {
+		case i == n-1 && t == typError:
+			kinds[i] = retError
+		case t == typResponse || t == typResponsePtr:
+			kinds[i] = retResponse
+		case t == typHeader:
+			kinds[i] = retHeader
+		case t == typCookie:
+			kinds[i] = retCookie
+		case t == typCookies:
+			kinds[i] = retCookies
+		case i == 0 && t == typInt && n >= 2 && !(n == 2 && typ.Out(1) == typError):
+			kinds[i] = retStatus
+		case !hasBody:
+			kinds[i] = retBody
+			hasBody = true
+		default:
+			kinds[i] = retIgnored
+		}
+	}
+	return kinds
+}
+
+// collectResults merges the results of a bind func into a Response.
+// Body of the Response is the reflect.Value of the body, or invalid.
+func collectResults(kinds []retKind, vals []reflect.Value) (*Response, reflect.Value, error) {
+	res := &Response{}
+	body := reflect.Value{}
+	var err error
+	for i, v := range vals {
+		switch kinds[i] {
+		case retError:
+			if e, ok := ValueToError(v); ok {
+				err = e
+			}
+		case retStatus:
+			res.Status = int(v.Int())
+		case retBody:
+			body = v
+		case retHeader:
+			res.mergeHeader(v.Interface().(http.Header))
+		case retCookie:
+			if c := v.Interface().(*http.Cookie); c != nil {
+				res.Cookies = append(res.Cookies, c)
+			}
+		case retCookies:
+			res.Cookies = append(res.Cookies, v.Interface().([]*http.Cookie)...)
+		case retResponse:
+			if v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					continue
+				}
+				v = v.Elem()
+			}
+			r := v.Interface().(Response)
+			if r.Status > 0 {
+				res.Status = r.Status
+			}
+			res.mergeHeader(r.Header)
+			res.Cookies = append(res.Cookies, r.Cookies...)
+			if r.Body != nil {
+				body = reflect.ValueOf(r.Body)
+			}
+		}
+	}
+	return res, body, err
+}
+
+func (r *Response) mergeHeader(h http.Header) {
+	if len(h) == 0 {
+		return
+	}
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	for name, values := range h {
+		r.Header[http.CanonicalHeaderKey(name)] = append([]string{}, values...)
+	}
+}
+
+// checkStatus reports a status of r which http.ResponseWriter can't write.
+// Zero means the default, 200.
+func (r *Response) checkStatus() error {
+	if r.Status != 0 && (r.Status < 100 || r.Status > 999) {
+		return fmt.Errorf("invalid status code %d.", r.Status)
+	}
+	return nil
+}
+
+// writeHeader writes the headers, cookies and status of r.
+func (r *Response) writeHeader(w http.ResponseWriter) {
+	for name, values := range r.Header {
+		w.Header()[name] = values
+	}
+	for _, c := range r.Cookies {
+		http.SetCookie(w, c)
+	}
+	status := r.Status
+	if status <= 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+}
@@ -0,0 +1,70 @@
+package kit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseProtocol(t *testing.T) {
+	cases := []struct {
+		fn     interface{}
+		status int
+		header string
+		cookie string
+		body   string
+	}{
+		{func() (int, string) { return 202, "queued" }, 202, "", "", "queued"},
+		{func() (int, error) { return 7, nil }, 200, "", "", "7"},
+		{func() (*Response, error) {
+			return &Response{
+				Status:  201,
+				Header:  http.Header{"location": {"/users/1"}},
+				Cookies: []*http.Cookie{{Name: "sid", Value: "x"}},
+				Body:    map[string]int{"id": 1},
+			}, nil
+		}, 201, "/users/1", "sid=x", `{"id":1}`},
+		{func() (string, http.Header, *http.Cookie) {
+			return "hi", http.Header{"Location": {"/hi"}}, &http.Cookie{Name: "a", Value: "b"}
+		}, 200, "/hi", "a=b", "hi"},
+		{func() (int, Response) { return 204, Response{} }, 204, "", "", ""},
+		{func() error { return nil }, 200, "", "", ""},
+		{func() error { return NewError(403, "no.") }, 403, "", "", "no."},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		F(c.fn)(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d", i, c.status, w.Code)
+			return
+		}
+		if loc := w.Header().Get("Location"); loc != c.header {
+			t.Fatalf("case %d: unexpected location: %s", i, loc)
+			return
+		}
+		if ck := w.Header().Get("Set-Cookie"); ck != c.cookie {
+			t.Fatalf("case %d: unexpected cookie: %s", i, ck)
+			return
+		}
+		if w.Body.String() != c.body {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestInvalidStatus(t *testing.T) {
+	handlers := []http.HandlerFunc{
+		F(func() (int, string) { return 42, "x" }),
+		F(func() *Response { return &Response{Status: 1000} }),
+		F(func(w http.ResponseWriter) { w.WriteHeader(42) }),
+	}
+	for i, h := range handlers {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("GET", "/", nil))
+		if w.Code != 500 {
+			t.Fatalf("case %d: expects 500. got %d", i, w.Code)
+			return
+		}
+	}
+}
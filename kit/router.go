@@ -0,0 +1,194 @@
+package kit
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Middleware wraps a http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+// Router routes requests by the method and path patterns of http.ServeMux,
+// e.g. "GET /users/{id}", whose wildcards are bound as path params.
+//
+// Requests which match no pattern are answered 404, and those which match
+// a pattern of other methods only are answered 405 with an Allow header,
+// both through the middlewares and the error renderer of the innermost
+// group containing the path, with PhaseRoute.
+type Router struct {
+	mux         *http.ServeMux
+	parent      *Router
+	prefix      string
+	middlewares []Middleware
+	binder      *Binder
+	groups      []*Router
+}
+
+var _ http.Handler = (*Router)(nil)
+
+// NewRouter returns a Router binding funcs with opts.
+func NewRouter(opts ...Option) *Router {
+	return &Router{
+		mux:    http.NewServeMux(),
+		binder: NewBinder(opts...),
+	}
+}
+
+// Group returns a Router registering its routes under prefix on the same
+// mux. A prefix without a leading slash is given one. Funcs of the group
+// are bound with the options of r followed by opts, and wrapped by the
+// middlewares of r and then by those of the group. Unmatched requests
+// under prefix are rendered by the error renderer of the group.
+func (r *Router) Group(prefix string, opts ...Option) *Router {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if len(prefix) > 0 && !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	g := &Router{
+		mux:    r.mux,
+		parent: r,
+		prefix: r.prefix + prefix,
+		binder: r.binder.With(opts...),
+	}
+	r.groups = append(r.groups, g)
+	return g
+}
+
+// routeGroup returns the innermost group of r whose prefix contains path,
+// or r.
+func (r *Router) routeGroup(path string) *Router {
+	for _, g := range r.groups {
+		if len(g.prefix) > len(r.prefix) && (path == g.prefix || strings.HasPrefix(path, g.prefix+"/")) {
+			return g.routeGroup(path)
+		}
+	}
+	return r
+}
+
+// Use adds middlewares to the routes registered on r and its groups
+// afterwards. The first middleware is the outermost.
+func (r *Router) Use(mws ...Middleware) {
+	r.middlewares = append(r.middlewares, mws...)
+}
+
+func (r *Router) wrap(h http.Handler) http.Handler {
+	for g := r; g != nil; g = g.parent {
+		for i := len(g.middlewares) - 1; i >= 0; i-- {
+			h = g.middlewares[i](h)
+		}
+	}
+	return h
+}
+
+// pattern inserts the prefix of r before the path of a ServeMux pattern
+// "[METHOD ][HOST]/PATH".
+func (r *Router) pattern(pattern string) string {
+	method, rest, found := strings.Cut(pattern, " ")
+	if !found {
+		method, rest = "", pattern
+	} else {
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	i := strings.Index(rest, "/")
+	if i < 0 {
+		return pattern // left to ServeMux to reject.
+	}
+	p := rest[:i] + r.prefix + rest[i:]
+	if len(method) > 0 {
+		return method + " " + p
+	}
+	return p
+}
+
+// Handle registers h for pattern, under the prefix of r.
+func (r *Router) Handle(pattern string, h http.Handler) {
+	r.mux.Handle(r.pattern(pattern), r.wrap(h))
+}
+
+// HandleFunc registers h for pattern, under the prefix of r.
+func (r *Router) HandleFunc(pattern string, h http.HandlerFunc) {
+	r.Handle(pattern, h)
+}
+
+// Bind registers the func fn, bound by BindFunc, for method and pattern.
+// An empty method matches every method.
+func (r *Router) Bind(method, pattern string, fn interface{}, opts ...Option) {
+	if len(method) > 0 {
+		pattern = method + " " + pattern
+	}
+	r.Handle(pattern, r.binder.BindFunc(fn, opts...))
+}
+
+// Get binds fn for GET, and so HEAD, requests of pattern.
+func (r *Router) Get(pattern string, fn interface{}, opts ...Option) {
+	r.Bind(http.MethodGet, pattern, fn, opts...)
+}
+
+// Post binds fn for POST requests of pattern.
+func (r *Router) Post(pattern string, fn interface{}, opts ...Option) {
+	r.Bind(http.MethodPost, pattern, fn, opts...)
+}
+
+// Put binds fn for PUT requests of pattern.
+func (r *Router) Put(pattern string, fn interface{}, opts ...Option) {
+	r.Bind(http.MethodPut, pattern, fn, opts...)
+}
+
+// Patch binds fn for PATCH requests of pattern.
+func (r *Router) Patch(pattern string, fn interface{}, opts ...Option) {
+	r.Bind(http.MethodPatch, pattern, fn, opts...)
+}
+
+// Delete binds fn for DELETE requests of pattern.
+func (r *Router) Delete(pattern string, fn interface{}, opts ...Option) {
+	r.Bind(http.MethodDelete, pattern, fn, opts...)
+}
+
+// ServeHTTP dispatches req to the handler of the matching pattern.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h, pattern := r.mux.Handler(req)
+	if len(pattern) > 0 {
+		r.mux.ServeHTTP(w, req)
+		return
+	}
+	// No pattern matches: h is the 404 or 405 handler of ServeMux. It is
+	// answered through the middlewares and the error renderer of the
+	// innermost group containing the path.
+	g := r.routeGroup(req.URL.Path)
+	rec := &headerRecorder{header: http.Header{}}
+	h.ServeHTTP(rec, req)
+	if rec.code < 400 {
+		g.wrap(r.mux).ServeHTTP(w, req)
+		return
+	}
+	err := &HTTPError{Status: rec.code}
+	if allow := rec.header.Values("Allow"); len(allow) > 0 {
+		err.Header = http.Header{"Allow": allow}
+	}
+	render := newBindOptions(g.binder.opts).errorRenderer()
+	g.wrap(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		render(w, req, err, PhaseRoute)
+	})).ServeHTTP(w, req)
+}
+
+// headerRecorder keeps the status and headers written to it, and drops
+// the body.
+type headerRecorder struct {
+	header http.Header
+	code   int
+}
+
+func (w *headerRecorder) Header() http.Header {
+	return w.header
+}
+
+func (w *headerRecorder) WriteHeader(code int) {
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+func (w *headerRecorder) Write(dat []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return len(dat), nil
+}
@@ -0,0 +1,124 @@
+package kit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testUserPath struct {
+	ID int `req:"id"`
+}
+
+func testHeaderMiddleware(name string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Add("X-Chain", name)
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+func TestRouter(t *testing.T) {
+	r := NewRouter(WithErrorRenderer(ProblemRenderer))
+	r.Use(testHeaderMiddleware("root"))
+	r.Get("/{$}", func() string { return "home" })
+
+	api := r.Group("/api/")
+	api.Use(testHeaderMiddleware("api"), func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent) // a preflight answer.
+				return
+			}
+			next.ServeHTTP(w, req)
+		})
+	})
+	api.Get("/users/{id}", func(p *testUserPath) int { return p.ID })
+	api.Delete("/users/{id}", func(p *testUserPath) (int, string) { return 202, "deleted" })
+	api.Post("/users", func(u *testXY) (int, *testXY) { return 201, u })
+	api.HandleFunc("GET example.com/host", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Pattern))
+	})
+
+	cases := []struct {
+		method string
+		target string
+		body   string
+		status int
+		out    string
+		chain  string
+		allow  string
+	}{
+		{"GET", "/", "", 200, "home", "root", ""},
+		{"GET", "/api/users/7", "", 200, "7", "root,api", ""},
+		{"HEAD", "/api/users/7", "", 200, "", "root,api", ""},
+		{"DELETE", "/api/users/7", "", 202, "deleted", "root,api", ""},
+		{"POST", "/api/users", `{"x":1,"y":2}`, 201, `{"x":1,"y":2}`, "root,api", ""},
+		{"GET", "http://example.com/api/host", "", 200, "GET example.com/api/host", "root,api", ""},
+		{"PUT", "/api/users/7", "", 405, `"status":405`, "root,api", "DELETE, GET, HEAD"},
+		{"GET", "/api/nothing", "", 404, `"status":404`, "root,api", ""},
+		{"GET", "/nothing", "", 404, `"status":404`, "root", ""},
+		{"OPTIONS", "/api/users/7", "", 204, "", "root,api", ""},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+		if len(c.body) > 0 {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d: %s", i, c.status, w.Code, w.Body.String())
+			return
+		}
+		if !strings.Contains(w.Body.String(), c.out) {
+			t.Fatalf("case %d: body is not expected: %s", i, w.Body.String())
+			return
+		}
+		if chain := strings.Join(w.Header().Values("X-Chain"), ","); chain != c.chain {
+			t.Fatalf("case %d: expects middlewares %s. got %s", i, c.chain, chain)
+			return
+		}
+		if allow := w.Header().Get("Allow"); allow != c.allow {
+			t.Fatalf("case %d: expects Allow %q. got %q", i, c.allow, allow)
+			return
+		}
+	}
+}
+
+func TestRouterGroupPrefix(t *testing.T) {
+	r := NewRouter(WithErrorRenderer(ProblemRenderer))
+	v1 := r.Group("v1")
+	v1.Get("/items/{id}", func(p *testUserPath) int { return p.ID })
+	text := v1.Group("text", WithErrorRenderer(TextErrorRenderer))
+	text.Get("/items/{id}", func(p *testUserPath) int { return p.ID })
+
+	cases := []struct {
+		method string
+		target string
+		status int
+		ct     string
+	}{
+		{"GET", "/v1/items/3", 200, "application/json"},
+		{"GET", "/v1/text/items/3", 200, "application/json"},
+		{"POST", "/v1/items/3", 405, "application/problem+json"},
+		{"GET", "/v1/nothing", 404, "application/problem+json"},
+		{"POST", "/v1/text/items/3", 405, "text/plain; charset=UTF-8"},
+		{"GET", "/v1/text/nothing", 404, "text/plain; charset=UTF-8"},
+		{"GET", "/v1/textual", 404, "application/problem+json"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(c.method, c.target, nil))
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d: %s", i, c.status, w.Code, w.Body.String())
+			return
+		}
+		if ct := w.Header().Get("Content-Type"); ct != c.ct {
+			t.Fatalf("case %d: unexpected content type: %s", i, ct)
+			return
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerEvent is a Server-Sent Event.
+type ServerEvent struct {
+	ID    string
+	Event string
+	Data  string
+	Retry time.Duration // asks the client to wait this long before reconnecting.
+}
+
+// EventStream writes Server-Sent Events to a response. The first write
+// sets Content-Type: text/event-stream unless a Content-Type is already
+// set. Every write is flushed. It is safe for concurrent use.
+type EventStream struct {
+	w       http.ResponseWriter
+	mu      sync.Mutex
+	started bool
+}
+
+// NewEventStream returns an EventStream writing to w.
+func NewEventStream(w http.ResponseWriter) *EventStream {
+	return &EventStream{w: w}
+}
+
+// Send writes ev. Multi-line data is sent as one data line per line.
+func (s *EventStream) Send(ev ServerEvent) error {
+	if strings.ContainsAny(ev.ID, "\r\n\x00") {
+		return fmt.Errorf("invalid event id: %q.", ev.ID)
+	}
+	if strings.ContainsAny(ev.Event, "\r\n") {
+		return fmt.Errorf("invalid event name: %q.", ev.Event)
+	}
+	b := &strings.Builder{}
+	if len(ev.ID) > 0 {
+		fmt.Fprintf(b, "id: %s\r\n", ev.ID)
+	}
+	if len(ev.Event) > 0 {
+		fmt.Fprintf(b, "event: %s\r\n", ev.Event)
+	}
+	if ev.Retry > 0 {
+		fmt.Fprintf(b, "retry: %d\r\n", ev.Retry.Milliseconds())
+	}
+	if len(ev.Data) > 0 || len(ev.Event) > 0 || b.Len() == 0 {
+		data := strings.ReplaceAll(ev.Data, "\r\n", "\n")
+		data = strings.ReplaceAll(data, "\r", "\n")
+		for _, line := range strings.Split(data, "\n") {
+			fmt.Fprintf(b, "data: %s\r\n", line)
+		}
+	}
+	b.WriteString("\r\n")
+	return s.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore. An empty comment
+// is a heartbeat that keeps idle connections open.
+func (s *EventStream) Comment(text string) error {
+	b := &strings.Builder{}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, ":%s\r\n", strings.TrimRight(line, "\r"))
+	}
+	b.WriteString("\r\n")
+	return s.write(b.String())
+}
+
+// Heartbeat writes an empty comment every interval until ctx is done or
+// a write fails.
+func (s *EventStream) Heartbeat(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Comment(""); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Flush sends the response headers, if not yet sent, and flushes.
+func (s *EventStream) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start()
+	s.flush()
+}
+
+func (s *EventStream) write(chunk string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.start()
+	if _, err := io.WriteString(s.w, chunk); err != nil {
+		return err
+	}
+	s.flush()
+	return nil
+}
+
+func (s *EventStream) start() {
+	if s.started {
+		return
+	}
+	s.started = true
+	h := s.w.Header()
+	if len(h.Get("Content-Type")) == 0 {
+		h.Set("Content-Type", "text/event-stream")
+	}
+	if len(h.Get("Cache-Control")) == 0 {
+		h.Set("Cache-Control", "no-cache")
+	}
+}
+
+func (s *EventStream) flush() {
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Broker fans events published to topics out to subscribers. Each
+// subscriber has a bounded buffer; a subscriber which falls behind is
+// dropped, and can catch up by reconnecting with Last-Event-ID, as the
+// last events of each topic are kept for replay.
+//
+// The zero Broker is ready to use: it buffers one event per subscriber,
+// keeps no history and sends no heartbeats. See NewBroker.
+type Broker struct {
+	Heartbeat time.Duration // interval of heartbeats written by ServeTopic. 0 disables them.
+	Retry     time.Duration // reconnection delay sent to clients by ServeTopic, if positive.
+
+	mu      sync.Mutex
+	buffer  int
+	history int
+	seq     uint64
+	topics  map[string]*brokerTopic
+}
+
+type brokerTopic struct {
+	subs map[*Subscription]struct{}
+	ring []ServerEvent
+	next int // index of the oldest event in ring once it is full.
+}
+
+// Subscription receives the events of a topic on C. C is closed when
+// the subscription is closed or dropped for falling behind.
+type Subscription struct {
+	C <-chan ServerEvent
+
+	c      chan ServerEvent
+	broker *Broker
+	topic  string
+	closed bool
+}
+
+// NewBroker returns a Broker which buffers up to buffer events for each
+// subscriber and keeps the last history events of each topic for replay.
+func NewBroker(buffer, history int) *Broker {
+	if buffer < 1 {
+		buffer = 1
+	}
+	return &Broker{
+		Heartbeat: 15 * time.Second,
+		buffer:    buffer,
+		history:   history,
+		topics:    map[string]*brokerTopic{},
+	}
+}
+
+func (b *Broker) topic(name string) *brokerTopic {
+	if b.topics == nil {
+		b.topics = map[string]*brokerTopic{}
+	}
+	t, ok := b.topics[name]
+	if !ok {
+		t = &brokerTopic{subs: map[*Subscription]struct{}{}}
+		b.topics[name] = t
+	}
+	return t
+}
+
+// Publish sends ev to the subscribers of topic. If ev has no ID it is
+// given one, unique within the broker. The published event is returned.
+func (b *Broker) Publish(topic string, ev ServerEvent) ServerEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.seq++
+	if len(ev.ID) == 0 {
+		ev.ID = strconv.FormatUint(b.seq, 10)
+	}
+	t := b.topic(topic)
+	if b.history > 0 {
+		if len(t.ring) < b.history {
+			t.ring = append(t.ring, ev)
+		} else {
+			t.ring[t.next] = ev
+			t.next = (t.next + 1) % b.history
+		}
+	}
+	for sub := range t.subs {
+		select {
+		case sub.c <- ev:
+		default:
+			b.drop(t, sub)
+		}
+	}
+	return ev
+}
+
+// history returns the kept events of t, oldest first.
+func (t *brokerTopic) history() []ServerEvent {
+	events := make([]ServerEvent, 0, len(t.ring))
+	events = append(events, t.ring[t.next:]...)
+	return append(events, t.ring[:t.next]...)
+}
+
+// Subscribe subscribes to topic. If lastEventID is not empty, the kept
+// events published after it are delivered first; all kept events are
+// delivered if it is no longer kept.
+func (b *Broker) Subscribe(topic, lastEventID string) *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := b.topic(topic)
+	replay := []ServerEvent{}
+	if len(lastEventID) > 0 {
+		replay = t.history()
+		for i, ev := range replay {
+			if ev.ID == lastEventID {
+				replay = replay[i+1:]
+				break
+			}
+		}
+	}
+	c := make(chan ServerEvent, max(b.buffer, 1)+len(replay))
+	for _, ev := range replay {
+		c <- ev
+	}
+	sub := &Subscription{C: c, c: c, broker: b, topic: topic}
+	t.subs[sub] = struct{}{}
+	return sub
+}
+
+// Close ends the subscription and closes C.
+func (s *Subscription) Close() {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t, ok := b.topics[s.topic]; ok {
+		b.drop(t, s)
+	}
+}
+
+func (b *Broker) drop(t *brokerTopic, sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(t.subs, sub)
+	close(sub.c)
+}
+
+// ServeTopic streams the events of topic to the client until the request
+// is cancelled or the client falls behind. It replays from the
+// Last-Event-ID header, or the lastEventId query parameter.
+func (b *Broker) ServeTopic(w http.ResponseWriter, req *http.Request, topic string) {
+	lastID := req.Header.Get("Last-Event-ID")
+	if len(lastID) == 0 {
+		lastID = req.URL.Query().Get("lastEventId")
+	}
+	sub := b.Subscribe(topic, lastID)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	es := NewEventStream(w)
+	if b.Retry > 0 {
+		if err := es.Send(ServerEvent{Retry: b.Retry}); err != nil {
+			return
+		}
+	} else {
+		es.Flush()
+	}
+	if b.Heartbeat > 0 {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			es.Heartbeat(ctx, b.Heartbeat)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := es.Send(ev); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Handler returns a handler serving topic with ServeTopic.
+func (b *Broker) Handler(topic string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b.ServeTopic(w, req, topic)
+	})
+}
@@ -0,0 +1,264 @@
+package kit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventReader parses a text/event-stream.
+type EventReader struct {
+	scanner *bufio.Scanner
+	started bool
+	lastID  string
+	retry   time.Duration
+}
+
+// NewEventReader returns an EventReader reading from r. lastEventID is
+// the id given to events until the stream sets one.
+func NewEventReader(r io.Reader, lastEventID string) *EventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	scanner.Split(scanEventLines)
+	return &EventReader{scanner: scanner, lastID: lastEventID}
+}
+
+// scanEventLines splits lines ended by CRLF, LF or CR.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Next returns the next event. Comments and blocks without data are
+// skipped, and an event left incomplete at the end of the stream is
+// discarded. It returns io.EOF at the end of the stream.
+func (r *EventReader) Next() (ServerEvent, error) {
+	name := ""
+	data := &strings.Builder{}
+	hasData := false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if !r.started {
+			r.started = true
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if len(line) == 0 {
+			if !hasData {
+				name = ""
+				continue
+			}
+			return ServerEvent{
+				ID:    r.lastID,
+				Event: name,
+				Data:  strings.TrimSuffix(data.String(), "\n"),
+			}, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			name = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				r.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return ServerEvent{}, err
+	}
+	return ServerEvent{}, io.EOF
+}
+
+// LastEventID returns the last id set by the stream.
+func (r *EventReader) LastEventID() string {
+	return r.lastID
+}
+
+// Retry returns the last reconnection delay set by the stream, or 0.
+func (r *EventReader) Retry() time.Duration {
+	return r.retry
+}
+
+// DefaultEventRetry is the reconnection delay of an EventSource until
+// the server sets one.
+var DefaultEventRetry = 3 * time.Second
+
+// EventSource consumes a Server-Sent Events endpoint. It reconnects when
+// the connection is lost, after the delay set by the server, sending the
+// last event id as Last-Event-ID.
+//
+// The exported fields are read when Events is called and are not changed
+// afterwards; CurrentEventID and CurrentRetry report the running state.
+type EventSource struct {
+	URL         string
+	Client      *http.Client  // http.DefaultClient if nil.
+	Header      http.Header   // extra headers of each request.
+	LastEventID string        // initial Last-Event-ID.
+	Retry       time.Duration // initial delay, DefaultEventRetry if 0.
+
+	mu     sync.Mutex
+	lastID string
+	retry  time.Duration
+	err    error
+}
+
+// NewEventSource returns an EventSource for url.
+func NewEventSource(url string) *EventSource {
+	return &EventSource{URL: url}
+}
+
+// Events connects and returns the channel of received events. The
+// channel is closed when ctx is done, when the server answers 204 No
+// Content, or when it answers with another status than 200 or with
+// another media type than text/event-stream; see Err.
+func (s *EventSource) Events(ctx context.Context) <-chan ServerEvent {
+	ch := make(chan ServerEvent)
+	s.mu.Lock()
+	s.lastID, s.retry = s.LastEventID, s.Retry
+	s.mu.Unlock()
+	go func() {
+		defer close(ch)
+		for {
+			retry, err := s.consume(ctx, ch)
+			if err != nil || ctx.Err() != nil {
+				s.setErr(err)
+				return
+			}
+			if !retry {
+				return
+			}
+			delay := s.CurrentRetry()
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+		}
+	}()
+	return ch
+}
+
+// CurrentEventID returns the id of the last event received, or the initial
+// LastEventID.
+func (s *EventSource) CurrentEventID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastID
+}
+
+// CurrentRetry returns the reconnection delay last set by the server, or
+// the initial Retry, or DefaultEventRetry.
+func (s *EventSource) CurrentRetry() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.retry <= 0 {
+		return DefaultEventRetry
+	}
+	return s.retry
+}
+
+// Err returns the error which closed the channel of Events, if any.
+func (s *EventSource) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *EventSource) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// consume reads one connection. It reports whether to reconnect, or an
+// error which must not be retried.
+func (s *EventSource) consume(ctx context.Context, ch chan<- ServerEvent) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return false, err
+	}
+	for k, values := range s.Header {
+		req.Header[k] = values
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	lastID := s.CurrentEventID()
+	if len(lastID) > 0 {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNoContent {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("event source: unexpected status %s.", resp.Status)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/event-stream" {
+		return false, fmt.Errorf("event source: unexpected content type %q.", mt)
+	}
+
+	r := NewEventReader(resp.Body, lastID)
+	for {
+		ev, err := r.Next()
+		s.mu.Lock()
+		s.lastID = r.LastEventID()
+		if r.Retry() > 0 {
+			s.retry = r.Retry()
+		}
+		s.mu.Unlock()
+		if err != nil {
+			return true, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case ch <- ev:
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package kit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEventReader(t *testing.T) {
+	stream := "\ufeff: comment\n" +
+		"retry: 1500\n" +
+		"event: greet\r\n" +
+		"data: hello\r\n" +
+		"data:  world\r\n" +
+		"id: 1\r\n\r\n" +
+		"data\rdata: x\r\r" +
+		"id: 2\n\n" +
+		"data:y\nid\n\n" +
+		"data: incomplete"
+	expects := []ServerEvent{
+		{ID: "1", Event: "greet", Data: "hello\n world"},
+		{ID: "1", Data: "\nx"},
+		{ID: "", Data: "y"},
+	}
+	r := NewEventReader(strings.NewReader(stream), "0")
+	for i, expect := range expects {
+		ev, err := r.Next()
+		if err != nil {
+			t.Fatalf("event %d: %v", i, err)
+			return
+		}
+		if ev != expect {
+			t.Fatalf("event %d: expects %+v. got %+v", i, expect, ev)
+			return
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expects EOF. got %v", err)
+		return
+	}
+	if r.Retry() != 1500*time.Millisecond {
+		t.Fatalf("retry is not parsed: %v", r.Retry())
+		return
+	}
+}
+
+func TestEventSourceReconnect(t *testing.T) {
+	mu := sync.Mutex{}
+	lastIDs := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		lastIDs = append(lastIDs, req.Header.Get("Last-Event-ID"))
+		n := len(lastIDs)
+		mu.Unlock()
+		switch n {
+		case 1:
+			es := NewEventStream(w)
+			es.Send(ServerEvent{Retry: 10 * time.Millisecond})
+			es.Send(ServerEvent{ID: "1", Data: "first"})
+		case 2:
+			Event(w, "", "second")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	src := NewEventSource(srv.URL)
+	got := []ServerEvent{}
+	for ev := range src.Events(ctx) {
+		got = append(got, ev)
+		if id := src.CurrentEventID(); id != ev.ID {
+			t.Fatalf("expects current event id %q. got %q", ev.ID, id)
+			return
+		}
+	}
+	if src.Err() != nil || ctx.Err() != nil {
+		t.Fatalf("unexpected end: %v %v", src.Err(), ctx.Err())
+		return
+	}
+	if len(got) != 2 || got[0].Data != "first" || got[1] != (ServerEvent{ID: "1", Data: "second"}) {
+		t.Fatalf("unexpected events: %+v", got)
+		return
+	}
+	if strings.Join(lastIDs, ",") != ",1,1" {
+		t.Fatalf("unexpected Last-Event-IDs: %q", lastIDs)
+		return
+	}
+	if src.LastEventID != "" || src.Retry != 0 || src.CurrentRetry() != 10*time.Millisecond {
+		t.Fatalf("unexpected state: %q %v %v", src.LastEventID, src.Retry, src.CurrentRetry())
+		return
+	}
+}
+
+func TestEventSourceBroker(t *testing.T) {
+	b := NewBroker(8, 8)
+	b.Heartbeat = 10 * time.Millisecond
+	b.Publish("t", ServerEvent{Data: "a"})
+	b.Publish("t", ServerEvent{Data: "b"})
+	srv := httptest.NewServer(b.Handler("t"))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	src := NewEventSource(srv.URL)
+	src.LastEventID = "1"
+	events := src.Events(ctx)
+	if ev := <-events; ev.ID != "2" || ev.Data != "b" {
+		t.Fatalf("replay is not expected: %+v", ev)
+		return
+	}
+	b.Publish("t", ServerEvent{Event: "c", Data: "c1\nc2"})
+	if ev := <-events; ev != (ServerEvent{ID: "3", Event: "c", Data: "c1\nc2"}) {
+		t.Fatalf("event is not expected: %+v", ev)
+		return
+	}
+	cancel()
+	for range events {
+	}
+}
+
+func TestEventSourceNotAStream(t *testing.T) {
+	srv := httptest.NewServer(F(func() string { return "hi" }))
+	defer srv.Close()
+	src := NewEventSource(srv.URL)
+	for range src.Events(context.Background()) {
+	}
+	if src.Err() == nil {
+		t.Fatalf("a text/plain response is accepted.")
+		return
+	}
+}
@@ -0,0 +1,165 @@
+package kit
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventStreamSend(t *testing.T) {
+	w := httptest.NewRecorder()
+	es := NewEventStream(w)
+	es.Send(ServerEvent{ID: "7", Event: "tick", Data: "a\nb\r\nc", Retry: 3 * time.Second})
+	es.Comment("")
+	es.Send(ServerEvent{Data: "x"})
+	expects := "id: 7\r\nevent: tick\r\nretry: 3000\r\ndata: a\r\ndata: b\r\ndata: c\r\n\r\n" +
+		":\r\n\r\n" +
+		"data: x\r\n\r\n"
+	if w.Body.String() != expects {
+		t.Fatalf("body is not expected: %q", w.Body.String())
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expects text/event-stream. got %s", ct)
+		return
+	}
+	if err := es.Send(ServerEvent{ID: "1\n2"}); err == nil {
+		t.Fatalf("an id with a new line is accepted.")
+		return
+	}
+
+	w = httptest.NewRecorder()
+	Event(w, "msg", "line1\nline2")
+	if w.Body.String() != "event: msg\r\ndata: line1\r\ndata: line2\r\n\r\n" {
+		t.Fatalf("multi-line event is not expected: %q", w.Body.String())
+		return
+	}
+}
+
+func TestBrokerReplay(t *testing.T) {
+	b := NewBroker(2, 3)
+	for _, data := range []string{"a", "b", "c", "d"} {
+		b.Publish("news", ServerEvent{Data: data})
+	}
+	b.Publish("other", ServerEvent{Data: "x"})
+
+	cases := []struct {
+		lastID string
+		expect string
+	}{
+		{"", ""},
+		{"3", "d"},
+		{"2", "cd"},
+		{"1", "bcd"}, // no longer kept, so all kept events.
+		{"4", ""},
+	}
+	for i, c := range cases {
+		sub := b.Subscribe("news", c.lastID)
+		got := ""
+		for len(sub.C) > 0 {
+			got += (<-sub.C).Data
+		}
+		sub.Close()
+		if got != c.expect {
+			t.Fatalf("case %d: expects %q. got %q", i, c.expect, got)
+			return
+		}
+		if _, ok := <-sub.C; ok {
+			t.Fatalf("case %d: channel is not closed.", i)
+			return
+		}
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(2, 0)
+	slow := b.Subscribe("t", "")
+	fast := b.Subscribe("t", "")
+	for i := 0; i < 3; i++ {
+		b.Publish("t", ServerEvent{Data: "x"})
+		<-fast.C
+	}
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != 2 {
+		t.Fatalf("expects 2 buffered events before dropping. got %d", n)
+		return
+	}
+	select {
+	case _, ok := <-fast.C:
+		t.Fatalf("fast subscriber is affected: %v", ok)
+		return
+	default:
+	}
+	fast.Close()
+}
+
+func TestBrokerZeroValue(t *testing.T) {
+	b := &Broker{}
+	sub := b.Subscribe("t", "")
+	if ev := b.Publish("t", ServerEvent{Data: "x"}); ev.ID != "1" {
+		t.Fatalf("unexpected id: %q", ev.ID)
+		return
+	}
+	if ev := <-sub.C; ev.Data != "x" {
+		t.Fatalf("unexpected event: %+v", ev)
+		return
+	}
+	b.Publish("other", ServerEvent{Data: "y"})
+	sub.Close()
+	if _, ok := <-sub.C; ok {
+		t.Fatalf("subscription is not closed.")
+		return
+	}
+}
+
+func TestBrokerServeTopic(t *testing.T) {
+	b := NewBroker(8, 8)
+	b.Heartbeat = 0
+	b.Retry = time.Second
+	b.Publish("t", ServerEvent{Data: "old"})
+	b.Publish("t", ServerEvent{Event: "greet", Data: "hello"})
+
+	srv := httptest.NewServer(b.Handler("t"))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+		return
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expects text/event-stream. got %s", ct)
+		return
+	}
+
+	go b.Publish("t", ServerEvent{Data: "live"})
+	expects := []string{
+		"retry: 1000", "",
+		"id: 2", "event: greet", "data: hello", "",
+		"id: 3", "data: live", "",
+	}
+	r := bufio.NewReader(resp.Body)
+	for i, expect := range expects {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("line %d: %v", i, err)
+			return
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != expect {
+			t.Fatalf("line %d: expects %q. got %q", i, expect, line)
+			return
+		}
+	}
+}
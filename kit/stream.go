@@ -0,0 +1,164 @@
+package kit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+)
+
+var typBytes = reflect.TypeOf([]byte(nil))
+
+// streamChunkSize is the size of chunks copied from an io.Reader result
+// before each flush.
+const streamChunkSize = 32 * 1024
+
+// writeStream writes results which are streamed rather than encoded:
+//
+//   - []byte is written as is, with a sniffed Content-Type.
+//   - io.Reader is copied in flushed chunks.
+//   - other io.WriterTo values are asked to write themselves.
+//   - a receivable chan is written item by item as NDJSON, or as SSE if
+//     the request prefers text/event-stream, until it is closed or the
+//     request is cancelled.
+//
+// A Content-Type set by the bound func, or in res, is kept. Readers and
+// writers which are also io.Closer are closed. It reports false if val
+// isn't one of these.
+func writeStream(w http.ResponseWriter, req *http.Request, res *Response, val reflect.Value) bool {
+	if val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return false
+		}
+		val = val.Elem()
+	}
+	if val.Type() == typBytes {
+		dat := val.Bytes()
+		writeStreamHeader(w, res, func() string { return http.DetectContentType(dat) })
+		w.Write(dat)
+		return true
+	}
+	if val.Kind() == reflect.Chan && val.Type().ChanDir()&reflect.RecvDir != 0 {
+		writeChan(w, req, res, val)
+		return true
+	}
+	if !val.CanInterface() {
+		return false
+	}
+	switch v := val.Interface().(type) {
+	case io.Reader:
+		if c, ok := v.(io.Closer); ok {
+			defer c.Close()
+		}
+		r := bufio.NewReaderSize(v, 512)
+		writeStreamHeader(w, res, func() string {
+			head, _ := r.Peek(512)
+			return http.DetectContentType(head)
+		})
+		if err := copyFlushing(w, r); err != nil {
+			slog.Warn("stream copy:", "err", err)
+		}
+		return true
+	case io.WriterTo:
+		if c, ok := v.(io.Closer); ok {
+			defer c.Close()
+		}
+		writeStreamHeader(w, res, func() string { return "application/octet-stream" })
+		if _, err := v.WriteTo(w); err != nil {
+			slog.Warn("stream WriteTo:", "err", err)
+		}
+		return true
+	}
+	return false
+}
+
+func writeStreamHeader(w http.ResponseWriter, res *Response, sniff func() string) {
+	if len(w.Header().Get("Content-Type")) == 0 && (res == nil || len(res.Header.Get("Content-Type")) == 0) {
+		w.Header().Set("Content-Type", sniff())
+	}
+	if res != nil {
+		res.writeHeader(w)
+	}
+}
+
+func copyFlushing(w http.ResponseWriter, r io.Reader) error {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, streamChunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if _, errW := w.Write(buf[:n]); errW != nil {
+				return errW
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func writeChan(w http.ResponseWriter, req *http.Request, res *Response, ch reflect.Value) {
+	ctx := context.Background()
+	sse := false
+	if req != nil {
+		ctx = req.Context()
+		w.Header().Add("Vary", "Accept")
+		accepts := parseAccept(req.Header.Get("Accept"))
+		sse = quality(accepts, "text/event-stream") > quality(accepts, "application/x-ndjson")
+	}
+	var es *EventStream
+	if sse {
+		writeStreamHeader(w, res, func() string { return "text/event-stream" })
+		es = NewEventStream(w)
+	} else {
+		writeStreamHeader(w, res, func() string { return "application/x-ndjson" })
+	}
+	flusher, _ := w.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+		{Dir: reflect.SelectRecv, Chan: ch},
+	}
+	for {
+		chosen, item, ok := reflect.Select(cases)
+		if chosen == 0 || !ok {
+			return
+		}
+		if ev, ok := item.Interface().(ServerEvent); ok && es != nil {
+			if err := es.Send(ev); err != nil {
+				slog.Warn("event:", "err", err)
+			}
+			continue
+		}
+		var line string
+		if item.Kind() == reflect.String && es != nil {
+			line = item.String()
+		} else if dat, err := json.Marshal(item.Interface()); err != nil {
+			slog.Warn("json.Marshal:", "value", item.Interface(), "err", err)
+			continue
+		} else {
+			line = string(dat)
+		}
+		if es != nil {
+			if err := es.Send(ServerEvent{Data: line}); err != nil {
+				slog.Warn("event:", "err", err)
+			}
+			continue
+		}
+		io.WriteString(w, line+"\n")
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
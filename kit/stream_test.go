@@ -0,0 +1,114 @@
+package kit
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testCloser struct {
+	io.Reader
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+type testWriterTo string
+
+func (s testWriterTo) WriteTo(w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, string(s))
+	return int64(n), err
+}
+
+func TestStreamResults(t *testing.T) {
+	items := func() <-chan testXY {
+		ch := make(chan testXY, 2)
+		ch <- testXY{X: 1}
+		ch <- testXY{X: 2, Y: 3}
+		close(ch)
+		return ch
+	}
+	words := func() <-chan string {
+		ch := make(chan string, 2)
+		ch <- "hello"
+		ch <- "world"
+		close(ch)
+		return ch
+	}
+
+	cases := []struct {
+		fn     interface{}
+		accept string
+		ct     string
+		out    string
+	}{
+		{func() []byte { return []byte("<html></html>") }, "", "text/html; charset=utf-8", "<html></html>"},
+		{func() []byte { return []byte("\x00\x01") }, "", "application/octet-stream", "\x00\x01"},
+		{func(w http.ResponseWriter) []byte {
+			w.Header().Set("Content-Type", "image/png")
+			return []byte("x")
+		}, "", "image/png", "x"},
+		{func() *Response {
+			return &Response{Header: http.Header{"Content-Type": {"text/css"}}, Body: []byte("a{}")}
+		}, "", "text/css", "a{}"},
+		{func() io.Reader { return strings.NewReader("plain text") }, "", "text/plain; charset=utf-8", "plain text"},
+		{func() io.WriterTo { return testWriterTo("data") }, "", "application/octet-stream", "data"},
+		{items, "", "application/x-ndjson", "{\"x\":1,\"y\":0}\n{\"x\":2,\"y\":3}\n"},
+		{items, "text/event-stream", "text/event-stream", "data: {\"x\":1,\"y\":0}\r\n\r\ndata: {\"x\":2,\"y\":3}\r\n\r\n"},
+		{words, "text/event-stream", "text/event-stream", "data: hello\r\n\r\ndata: world\r\n\r\n"},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/", nil)
+		if len(c.accept) > 0 {
+			req.Header.Set("Accept", c.accept)
+		}
+		F(c.fn)(w, req)
+		if w.Code != 200 {
+			t.Fatalf("case %d: expects 200. got %d: %s", i, w.Code, w.Body.String())
+			return
+		}
+		if ct := w.Header().Get("Content-Type"); ct != c.ct {
+			t.Fatalf("case %d: expects content type %s. got %s", i, c.ct, ct)
+			return
+		}
+		if w.Body.String() != c.out {
+			t.Fatalf("case %d: body is not expected: %q", i, w.Body.String())
+			return
+		}
+	}
+}
+
+func TestStreamReaderClosed(t *testing.T) {
+	rc := &testCloser{Reader: strings.NewReader("abc")}
+	w := httptest.NewRecorder()
+	F(func() io.ReadCloser { return rc })(w, httptest.NewRequest("GET", "/", nil))
+	if !rc.closed || w.Body.String() != "abc" {
+		t.Fatalf("reader is not copied and closed: %q", w.Body.String())
+		return
+	}
+}
+
+func TestStreamChanCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	h := F(func() <-chan int {
+		ch := make(chan int)
+		go func() {
+			ch <- 1
+			cancel()
+		}()
+		return ch
+	})
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/", nil).WithContext(ctx))
+	if w.Body.String() != "1\n" {
+		t.Fatalf("body is not expected: %q", w.Body.String())
+		return
+	}
+}
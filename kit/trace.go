@@ -0,0 +1,86 @@
+package kit
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/smallfz/httpkit/trace"
+)
+
+// requestTrace holds the spans of a request to a bound func: the span of
+// the request, and the child span of the current step.
+type requestTrace struct {
+	tracer trace.Tracer
+	ctx    context.Context // carries span.
+	span   trace.Span
+	step   trace.Span
+	err    error
+}
+
+// startTrace puts the span context of the traceparent header of req into
+// its context, unless it already carries a span. If a Tracer is set, it
+// also starts the span of the request, which bound funcs find in the
+// request context; otherwise the returned requestTrace is nil.
+func startTrace(req *http.Request) (*http.Request, *requestTrace) {
+	ctx := req.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if sc, ok := trace.Extract(req.Header); ok {
+			ctx = trace.ContextWithRemote(ctx, sc)
+		}
+	}
+	tracer := trace.GetTracer()
+	if tracer == nil {
+		if ctx != req.Context() {
+			req = req.WithContext(ctx)
+		}
+		return req, nil
+	}
+	name := req.Pattern
+	if len(name) == 0 {
+		name = req.Method
+	}
+	ctx, span := tracer.Start(ctx, name)
+	span.SetAttribute("http.request.method", req.Method)
+	span.SetAttribute("url.path", req.URL.Path)
+	if len(req.Pattern) > 0 {
+		span.SetAttribute("http.route", req.Pattern)
+	}
+	return req.WithContext(ctx), &requestTrace{tracer: tracer, ctx: ctx, span: span}
+}
+
+// enter ends the span of the current step, and starts the one of step.
+func (t *requestTrace) enter(step string) {
+	if t == nil {
+		return
+	}
+	if t.step != nil {
+		t.step.End()
+	}
+	_, t.step = t.tracer.Start(t.ctx, step)
+}
+
+// recordErrors returns render, recording the errors it renders in the
+// spans.
+func (t *requestTrace) recordErrors(render ErrorRenderer) ErrorRenderer {
+	return func(w http.ResponseWriter, req *http.Request, err error, phase Phase) {
+		t.err = err
+		if t.step != nil {
+			t.step.RecordError(err)
+		}
+		t.span.SetAttribute("kit.phase", string(phase))
+		render(w, req, err, phase)
+	}
+}
+
+// end ends the spans, once the response is done.
+func (t *requestTrace) end(w *monitoredWriter) {
+	if t.step != nil {
+		t.step.End()
+	}
+	stats := w.Stats()
+	if stats.Status > 0 {
+		t.span.SetAttribute("http.response.status_code", stats.Status)
+	}
+	t.span.RecordError(t.err)
+	t.span.End()
+}
@@ -0,0 +1,107 @@
+package kit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/smallfz/httpkit/trace"
+	"github.com/smallfz/httpkit/ws"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestTraceSpans(t *testing.T) {
+	mu := sync.Mutex{}
+	spans := []*trace.SpanData{}
+	trace.SetTracer(trace.NewTracer(func(d *trace.SpanData) {
+		mu.Lock()
+		defer mu.Unlock()
+		spans = append(spans, d)
+	}))
+	defer trace.SetTracer(nil)
+
+	var seen trace.SpanContext
+	r := NewRouter()
+	r.Get("/items/{id}", func(ctx context.Context, p *testUserPath) (int, error) {
+		seen = trace.SpanContextFromContext(ctx)
+		if p.ID == 0 {
+			return 0, fmt.Errorf("boom")
+		}
+		return p.ID, nil
+	})
+
+	req := httptest.NewRequest("GET", "/items/7", nil)
+	req.Header.Set("Traceparent", testTraceparent)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	names := []string{}
+	for _, d := range spans {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, ",") != "bind,handler,render,GET /items/{id}" {
+		t.Fatalf("unexpected spans: %v", names)
+		return
+	}
+	root := spans[3]
+	if root.Parent.String() != "00f067aa0ba902b7" || root.Context.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("request span is not a child of the traceparent: %+v", root)
+		return
+	}
+	if seen != root.Context {
+		t.Fatalf("bound func doesn't get the request span: %+v", seen)
+		return
+	}
+	if root.Attributes["http.response.status_code"] != 200 || root.Attributes["http.route"] != "GET /items/{id}" {
+		t.Fatalf("unexpected attributes: %v", root.Attributes)
+		return
+	}
+	for _, d := range spans[:3] {
+		if d.Parent != root.Context.SpanID {
+			t.Fatalf("%s is not a child of the request span.", d.Name)
+			return
+		}
+	}
+
+	spans = spans[:0]
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/items/0", nil))
+	if len(spans) != 3 || len(spans[1].Errors) != 1 || len(spans[2].Errors) != 1 || spans[2].Parent.IsValid() {
+		t.Fatalf("unexpected spans of a failed request: %+v", spans)
+		return
+	}
+}
+
+func TestTraceWebSocket(t *testing.T) {
+	received := make(chan trace.SpanContext, 2)
+	srv := httptest.NewServer(F(func(w http.ResponseWriter, req *http.Request) {
+		conn, err := ws.WebSocketHandshake(req, w)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		received <- ws.TraceContext(conn)
+	}))
+	defer srv.Close()
+
+	sc, _ := trace.ParseTraceparent(testTraceparent)
+	ctx := trace.ContextWithRemote(context.Background(), sc)
+	uri := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, err := ws.DialWithOptions(uri, &ws.DialOptions{Context: ctx})
+	if err != nil {
+		t.Fatalf("ws.DialWithOptions: %v", err)
+		return
+	}
+	defer conn.Close()
+	if got := <-received; got.Traceparent() != testTraceparent {
+		t.Fatalf("traceparent is not received: %s", got.Traceparent())
+		return
+	}
+	if ws.TraceContext(conn) != sc {
+		t.Fatalf("traceparent is not kept by the client conn.")
+		return
+	}
+}
@@ -0,0 +1,358 @@
+package kit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validator may be implemented by a payload type. Validate is called after
+// the payload is filled and its validate tags passed.
+type Validator interface {
+	Validate() error
+}
+
+var typValidator = reflect.TypeOf((*Validator)(nil)).Elem()
+
+// FieldError describes a single failing field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field of a payload which failed validation.
+// It is answered with status 422.
+type ValidationError struct {
+	Fields []FieldError
+	Err    error // the error returned by Validator, if any.
+}
+
+var _ StatusError = (*ValidationError)(nil)
+var _ ProblemExtender = (*ValidationError)(nil)
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields)+1)
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s %s", f.Field, f.Message))
+	}
+	if e.Err != nil {
+		msgs = append(msgs, e.Err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) ProblemExtensions() map[string]interface{} {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return map[string]interface{}{"errors": e.Fields}
+}
+
+// Validate checks the validate tags of struct v, including those of its
+// nested and embedded struct fields, then calls its Validate method if it
+// implements Validator. Fields of a nested struct are reported as
+// name.field.
+//
+// Supported rules, separated by comma:
+//
+//	required        non-zero value, non-empty string, slice or map.
+//	min=N, max=N    bounds of a number, or of the length of a string, slice or map.
+//	len=N           exact length of a string, slice or map.
+//	oneof=a b c     value is one of the space separated items.
+//	regex=EXPR      string matches EXPR. It must be the last rule.
+func Validate(v interface{}) error {
+	if v == nil {
+		return fmt.Errorf("nil input.")
+	}
+	val := reflect.ValueOf(v)
+	for val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fmt.Errorf("nil input.")
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return fmt.Errorf("target must be a struct or *struct.")
+	}
+	plan, err := validationPlanOf(val.Type())
+	if err != nil {
+		return err
+	}
+	return plan.validate(val)
+}
+
+type rule struct {
+	name  string
+	msg   string
+	check func(v reflect.Value) bool
+}
+
+type fieldRules struct {
+	index  int
+	name   string
+	rules  []rule
+	nested *validationPlan // of a nested or embedded struct field.
+}
+
+type validationPlan struct {
+	fields []fieldRules
+}
+
+type validationPlanEntry struct {
+	plan *validationPlan
+	err  error
+}
+
+var validationPlans sync.Map // reflect.Type -> *validationPlanEntry
+
+func validationPlanOf(typ reflect.Type) (*validationPlan, error) {
+	if e, ok := validationPlans.Load(typ); ok {
+		entry := e.(*validationPlanEntry)
+		return entry.plan, entry.err
+	}
+	plan, err := compileValidation(typ)
+	e, _ := validationPlans.LoadOrStore(typ, &validationPlanEntry{plan, err})
+	entry := e.(*validationPlanEntry)
+	return entry.plan, entry.err
+}
+
+func compileValidation(typ reflect.Type) (*validationPlan, error) {
+	return compileValidationNested(typ, "", map[reflect.Type]bool{})
+}
+
+// compileValidationNested compiles the validate tags of typ and of its
+// nested and embedded struct fields, like compileNested does for binding:
+// fields of a nested struct are named prefix.name, those of an embedded
+// one keep their own names. seen guards against recursive types.
+func compileValidationNested(typ reflect.Type, prefix string, seen map[reflect.Type]bool) (*validationPlan, error) {
+	plan := &validationPlan{}
+	seen[typ] = true
+	defer delete(seen, typ)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		embedded := f.Anonymous && !hasNameTag(f)
+		if !f.IsExported() && !(embedded && f.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		fr := fieldRules{index: i, name: prefix + paramName(f)}
+		if tag, ok := f.Tag.Lookup("validate"); ok && f.IsExported() {
+			rules, err := compileRules(f.Type, tag)
+			if err != nil {
+				return nil, fmt.Errorf("%v.%s: %v", typ, f.Name, err)
+			}
+			fr.rules = rules
+		}
+		if elem := f.Type; nestable(elem) {
+			if elem.Kind() == reflect.Ptr {
+				elem = elem.Elem()
+			}
+			if !seen[elem] {
+				sub := fr.name + "."
+				if embedded {
+					sub = prefix
+				}
+				nested, err := compileValidationNested(elem, sub, seen)
+				if err != nil {
+					return nil, err
+				}
+				if len(nested.fields) > 0 {
+					fr.nested = nested
+				}
+			}
+		}
+		if len(fr.rules) > 0 || fr.nested != nil {
+			plan.fields = append(plan.fields, fr)
+		}
+	}
+	return plan, nil
+}
+
+func compileRules(typ reflect.Type, tag string) ([]rule, error) {
+	rules := []rule{}
+	for len(tag) > 0 {
+		item := tag
+		if strings.HasPrefix(item, "regex=") {
+			tag = ""
+		} else if i := strings.Index(item, ","); i >= 0 {
+			item, tag = item[:i], item[i+1:]
+		} else {
+			tag = ""
+		}
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+		name, arg, _ := strings.Cut(item, "=")
+		r, err := compileRule(typ, name, arg)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func compileRule(typ reflect.Type, name, arg string) (rule, error) {
+	r := rule{name: name}
+	if name == "required" {
+		r.msg = "is required"
+		r.check = func(v reflect.Value) bool {
+			if !v.IsValid() {
+				return false
+			}
+			switch v.Kind() {
+			case reflect.Slice, reflect.Map, reflect.String:
+				return v.Len() > 0
+			}
+			return !v.IsZero()
+		}
+		return r, nil
+	}
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	kind := typ.Kind()
+	isLen := kind == reflect.String || kind == reflect.Slice || kind == reflect.Map || kind == reflect.Array
+	number := func(v reflect.Value) float64 {
+		switch v.Kind() {
+		case reflect.String:
+			return float64(utf8.RuneCountInString(v.String()))
+		case reflect.Slice, reflect.Map, reflect.Array:
+			return float64(v.Len())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			return float64(v.Uint())
+		}
+		return v.Float()
+	}
+	isNumber := false
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64:
+		isNumber = true
+	}
+
+	switch name {
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return r, fmt.Errorf("invalid %s=%s", name, arg)
+		}
+		if !isNumber && !isLen || name == "len" && !isLen {
+			return r, fmt.Errorf("%s is not applicable to %v", name, typ)
+		}
+		switch {
+		case name == "len":
+			r.msg = fmt.Sprintf("length must be %s", arg)
+			r.check = func(v reflect.Value) bool { return number(v) == n }
+		case name == "min" && isLen:
+			r.msg = fmt.Sprintf("length must be at least %s", arg)
+			r.check = func(v reflect.Value) bool { return number(v) >= n }
+		case name == "min":
+			r.msg = fmt.Sprintf("must be at least %s", arg)
+			r.check = func(v reflect.Value) bool { return number(v) >= n }
+		case isLen:
+			r.msg = fmt.Sprintf("length must be at most %s", arg)
+			r.check = func(v reflect.Value) bool { return number(v) <= n }
+		default:
+			r.msg = fmt.Sprintf("must be at most %s", arg)
+			r.check = func(v reflect.Value) bool { return number(v) <= n }
+		}
+	case "oneof":
+		items := strings.Fields(arg)
+		r.msg = fmt.Sprintf("must be one of [%s]", strings.Join(items, " "))
+		r.check = func(v reflect.Value) bool {
+			s := fmt.Sprint(v.Interface())
+			for _, item := range items {
+				if s == item {
+					return true
+				}
+			}
+			return false
+		}
+	case "regex":
+		if kind != reflect.String {
+			return r, fmt.Errorf("regex is not applicable to %v", typ)
+		}
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return r, fmt.Errorf("invalid regex=%s: %v", arg, err)
+		}
+		r.msg = fmt.Sprintf("must match %s", arg)
+		r.check = func(v reflect.Value) bool { return re.MatchString(v.String()) }
+	default:
+		return r, fmt.Errorf("unknown validate rule `%s`", name)
+	}
+	return r, nil
+}
+
+func (p *validationPlan) validate(val reflect.Value) error {
+	if fields := p.check(val, nil); len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	if val.CanAddr() && val.Addr().Type().Implements(typValidator) {
+		return validatorError(val.Addr().Interface().(Validator).Validate())
+	} else if val.Type().Implements(typValidator) {
+		return validatorError(val.Interface().(Validator).Validate())
+	}
+	return nil
+}
+
+// check appends the failing fields of struct val to fields.
+func (p *validationPlan) check(val reflect.Value, fields []FieldError) []FieldError {
+	for _, f := range p.fields {
+		fv := val.Field(f.index)
+		for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		failed := false
+		for _, r := range f.rules {
+			if fv.Kind() == reflect.Ptr && r.name != "required" {
+				continue // nil pointers are only checked by required.
+			}
+			if fv.Kind() == reflect.Ptr || !r.check(fv) {
+				fields = append(fields, FieldError{
+					Field:   f.name,
+					Rule:    r.name,
+					Message: r.msg,
+				})
+				failed = true
+				break
+			}
+		}
+		if !failed && f.nested != nil && fv.Kind() == reflect.Struct {
+			fields = f.nested.check(fv, fields)
+		}
+	}
+	return fields
+}
+
+func validatorError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var se StatusError
+	if errors.As(err, &se) {
+		return err
+	}
+	return &ValidationError{Err: err}
+}
@@ -0,0 +1,158 @@
+package kit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+)
+
+type testSearch struct {
+	Q     string `req:"q" validate:"required,max=8"`
+	Page  int    `req:"page" validate:"min=1,max=100"`
+	Sort  string `req:"sort" validate:"oneof=asc desc"`
+	Code  string `req:"code" validate:"regex=^[a-z]{2,3}$"`
+	Token string `req:"token" validate:"len=4"`
+}
+
+func (s *testSearch) Validate() error {
+	if s.Q == "forbidden" {
+		return fmt.Errorf("q is forbidden")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	ok := &testSearch{Q: "go", Page: 1, Sort: "asc", Code: "en", Token: "abcd"}
+	if err := Validate(ok); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+		return
+	}
+
+	err := Validate(&testSearch{Page: 101, Sort: "up", Code: "EN", Token: "abc"})
+	ve, isVe := err.(*ValidationError)
+	if !isVe {
+		t.Fatalf("expects *ValidationError. got %v", err)
+		return
+	}
+	fields := []string{}
+	for _, f := range ve.Fields {
+		fields = append(fields, f.Field+":"+f.Rule)
+	}
+	if fmt.Sprint(fields) != "[q:required page:max sort:oneof code:regex token:len]" {
+		t.Fatalf("unexpected fields: %v", fields)
+		return
+	}
+
+	ok.Q = "forbidden"
+	if err := Validate(ok); err == nil || ErrorStatus(err, 0) != 422 {
+		t.Fatalf("expects a 422 error from Validate(). got %v", err)
+	}
+}
+
+func TestValidateBindFunc(t *testing.T) {
+	h := F(func(s *testSearch) string {
+		return s.Q
+	}, WithErrorRenderer(ProblemRenderer))
+
+	w := httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?q=golang&page=1&sort=desc&code=de&token=1234", nil))
+	if w.Code != 200 || w.Body.String() != "golang" {
+		t.Fatalf("unexpected response: %d %s", w.Code, w.Body.String())
+		return
+	}
+
+	w = httptest.NewRecorder()
+	h(w, httptest.NewRequest("GET", "/?page=0&sort=desc&code=de&token=1234", nil))
+	if w.Code != 422 {
+		t.Fatalf("expects 422. got %d", w.Code)
+		return
+	}
+	p := struct {
+		Errors []FieldError `json:"errors"`
+	}{}
+	if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+		return
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "q" || p.Errors[1].Field != "page" {
+		t.Fatalf("unexpected problem: %s", w.Body.String())
+	}
+}
+
+func TestValidateInvalidTag(t *testing.T) {
+	type bad struct {
+		N int `validate:"len=3"`
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatalf("expects a panic for invalid validate tag.")
+		}
+	}()
+	F(func(p *bad) string { return "" })
+}
+
+type testPage struct {
+	Page int `req:"page" validate:"min=1"`
+}
+
+type testAddr struct {
+	City string `req:"city" validate:"required"`
+	Zip  string `req:"zip" validate:"len=5"`
+}
+
+type testOrder struct {
+	testPage
+	Addr testAddr  `req:"addr"`
+	Ship *testAddr `req:"ship"`
+}
+
+func TestValidateNested(t *testing.T) {
+	err := Validate(&testOrder{testPage: testPage{Page: 0}, Addr: testAddr{Zip: "123"}})
+	ve, isVe := err.(*ValidationError)
+	if !isVe {
+		t.Fatalf("expects *ValidationError. got %v", err)
+		return
+	}
+	fields := []string{}
+	for _, f := range ve.Fields {
+		fields = append(fields, f.Field+":"+f.Rule)
+	}
+	if fmt.Sprint(fields) != "[page:min addr.city:required addr.zip:len]" {
+		t.Fatalf("unexpected fields: %v", fields)
+		return
+	}
+
+	err = Validate(&testOrder{
+		testPage: testPage{Page: 1},
+		Addr:     testAddr{City: "x", Zip: "12345"},
+		Ship:     &testAddr{Zip: "12345"},
+	})
+	ve, isVe = err.(*ValidationError)
+	if !isVe || len(ve.Fields) != 1 || ve.Fields[0].Field != "ship.city" {
+		t.Fatalf("expects ship.city to fail. got %v", err)
+		return
+	}
+}
+
+func TestValidateNestedBindFunc(t *testing.T) {
+	h := F(func(o *testOrder) string { return o.Addr.City })
+
+	cases := []struct {
+		query  string
+		status int
+	}{
+		{"?page=1&addr.city=x&addr.zip=12345", 200},
+		{"?page=0&addr.city=x&addr.zip=12345", 422},
+		{"?page=1&addr.zip=12345", 422},
+		{"?page=1&addr[city]=x&addr[zip]=12345&ship.zip=1", 422},
+	}
+	for i, c := range cases {
+		w := httptest.NewRecorder()
+		h(w, httptest.NewRequest("GET", "/"+c.query, nil))
+		if w.Code != c.status {
+			t.Fatalf("case %d: expects %d. got %d: %s", i, c.status, w.Code, w.Body.String())
+			return
+		}
+	}
+}
@@ -1,76 +1,278 @@
 package kit
 
 import (
-	"encoding/json"
-	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
 )
 
+// MultipartMemory is the default number of bytes of a multipart/form-data
+// body kept in memory. The rest of the parts is stored in temporary files.
+var MultipartMemory int64 = 32 << 20
+
 type valueExtractor struct {
 	req   *http.Request
 	bodyb []byte
+
+	maxMemory  int64
+	strict     bool // report params which fail to convert.
+	streaming  bool // the body is left to a *multipart.Reader argument.
+	formParsed bool
+	formErr    error
+	query      url.Values
+	closers    []io.Closer
+}
+
+func newValueExtractor(req *http.Request, o *bindOptions, streaming bool) *valueExtractor {
+	return &valueExtractor{
+		req:       req,
+		maxMemory: o.multipartMemory(),
+		strict:    !o.lenient,
+		streaming: streaming,
+	}
 }
 
-func newValueExtractor(req *http.Request) *valueExtractor {
-	return &valueExtractor{req: req}
+// close closes everything opened while binding, e.g. uploaded files.
+func (x *valueExtractor) close() {
+	for _, c := range x.closers {
+		c.Close()
+	}
+	x.closers = nil
 }
 
-func (x *valueExtractor) unmarshalPathAndForm(target interface{}) error {
-	err := UnmarshalParams(target, func(tag string) interface{} {
-		name := tag
-		parts := strings.Split(name, ",")
-		if len(parts) > 1 {
-			name = parts[0]
+func (x *valueExtractor) parseForm() error {
+	if x.formParsed {
+		return x.formErr
+	}
+	x.formParsed = true
+	if x.streaming {
+		return nil
+	}
+	mt, _, _ := mime.ParseMediaType(x.req.Header.Get("Content-Type"))
+	if mt == "multipart/form-data" {
+		if err := x.req.ParseMultipartForm(x.maxMemory); err != nil {
+			x.formErr = WrapError(http.StatusBadRequest, err, "invalid multipart form.")
 		}
-		if v := x.req.FormValue(name); len(v) > 0 {
-			return v
+	} else {
+		x.req.ParseForm()
+		if mt == "application/x-www-form-urlencoded" && !readsFormBody(x.req.Method) {
+			x.formErr = x.parseBodyForm()
 		}
-		return x.req.PathValue(name)
-	})
+	}
+	return x.formErr
+}
+
+// readsFormBody reports if http.Request.ParseForm reads the urlencoded
+// body of requests of method.
+func readsFormBody(method string) bool {
+	switch method {
+	case "POST", "PUT", "PATCH":
+		return true
+	}
+	return false
+}
+
+// parseBodyForm adds the urlencoded body of a request ParseForm doesn't
+// read to its PostForm and Form, so that a single params pass binds it.
+func (x *valueExtractor) parseBodyForm() error {
+	dat, err := io.ReadAll(x.req.Body)
+	if err != nil {
+		return err
+	}
+	x.bodyb = dat
+	values, err := url.ParseQuery(string(dat))
 	if err != nil {
-		return fmt.Errorf("unmarshalRequest: %v", err)
+		return WrapError(http.StatusBadRequest, err, "invalid form body.")
+	}
+	for name, vs := range values {
+		x.req.PostForm[name] = append(x.req.PostForm[name], vs...)
+		x.req.Form[name] = append(append([]string{}, vs...), x.req.Form[name]...)
+	}
+	return nil
+}
+
+func (x *valueExtractor) queryValues() url.Values {
+	if x.query == nil {
+		x.query = x.req.URL.Query()
+	}
+	return x.query
+}
+
+func (x *valueExtractor) formFiles(name string) interface{} {
+	if mf := x.req.MultipartForm; mf != nil {
+		if files := mf.File[name]; len(files) > 0 {
+			return files
+		}
 	}
 	return nil
 }
 
-func (x *valueExtractor) unmarshalJSON(target interface{}) (bool, error) {
-	ct := strings.ToLower(x.req.Header.Get("Content-Type"))
-	if strings.Index(ct, "application/json") >= 0 {
-		if x.bodyb == nil {
-			dat, err := io.ReadAll(x.req.Body)
+// lookupValues returns all values of name, or the values of bracket
+// notation keys like name[key] as map[string][]string. It returns nil if
+// there is neither.
+func lookupValues(values url.Values, name string) interface{} {
+	if vs, ok := values[name]; ok {
+		return vs
+	}
+	prefix := name + "["
+	m := map[string][]string(nil)
+	for k, vs := range values {
+		if strings.HasPrefix(k, prefix) && strings.HasSuffix(k, "]") {
+			if m == nil {
+				m = map[string][]string{}
+			}
+			key := k[len(prefix) : len(k)-1]
+			m[key] = append(m[key], vs...)
+		}
+	}
+	if m == nil {
+		return nil
+	}
+	return m
+}
+
+func (x *valueExtractor) findParam(src Source, name string) interface{} {
+	switch src {
+	case SourcePath:
+		if v := x.req.PathValue(name); len(v) > 0 {
+			return v
+		}
+		return nil
+	case SourceQuery:
+		return lookupValues(x.queryValues(), name)
+	case SourceForm:
+		if x.streaming {
+			return nil
+		}
+		if v := lookupValues(x.req.PostForm, name); v != nil {
+			return v
+		}
+		return x.formFiles(name)
+	case SourceHeader:
+		if vs := x.req.Header.Values(name); len(vs) > 0 {
+			return vs
+		}
+		return nil
+	case SourceCookie:
+		if c, err := x.req.Cookie(name); err == nil {
+			return c.Value
+		}
+		return nil
+	}
+	values := x.req.Form
+	if x.streaming {
+		values = x.queryValues()
+	}
+	v := lookupValues(values, name)
+	if vs, ok := v.([]string); v != nil && (!ok || len(vs) > 0 && len(vs[0]) > 0) {
+		return v
+	}
+	if files := x.formFiles(name); files != nil {
+		return files
+	}
+	if pv := x.req.PathValue(name); len(pv) > 0 {
+		return pv
+	}
+	return v // nil if absent, or the empty values given explicitly.
+}
+
+func (x *valueExtractor) unmarshalPathAndForm(tv reflect.Value, plan *paramsPlan) error {
+	if plan == nil {
+		plan = paramsPlanOf(tv.Type())
+	}
+	if err := x.parseForm(); err != nil {
+		return err
+	}
+	if !x.strict {
+		plan.apply(tv, x.findParam, nil)
+		return x.openFiles(tv, plan)
+	}
+	errs := BindErrors(nil)
+	plan.apply(tv, x.findParam, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return x.openFiles(tv, plan)
+}
+
+// openFiles sets io.ReadCloser fields to the first uploaded file of their name.
+// The files are closed by close.
+func (x *valueExtractor) openFiles(tv reflect.Value, plan *paramsPlan) error {
+	mf := x.req.MultipartForm
+	if mf == nil || len(plan.readers) == 0 {
+		return nil
+	}
+	for _, f := range plan.readers {
+		fv := tv.Field(f.index)
+		name, ok := f.fileName()
+		if !ok || !fv.IsNil() {
+			continue
+		}
+		if files := mf.File[name]; len(files) > 0 {
+			file, err := files[0].Open()
 			if err != nil {
-				return true, err
+				return WrapError(http.StatusBadRequest, err, "invalid multipart form.")
 			}
-			x.bodyb = dat
+			x.closers = append(x.closers, file)
+			fv.Set(reflect.ValueOf(file))
+		}
+	}
+	return nil
+}
+
+// decodeBody decodes the body into target by the decoder of its
+// Content-Type. Bodies without a Content-Type and form bodies already
+// parsed by parseForm are left alone.
+func (x *valueExtractor) decodeBody(target interface{}) error {
+	if x.streaming {
+		return nil
+	}
+	mt := mediaTypeOf(x.req.Header.Get("Content-Type"))
+	switch mt {
+	case "", "multipart/form-data":
+		return nil
+	case "application/x-www-form-urlencoded":
+		if x.formParsed && x.formErr == nil {
+			return nil // bound with the other params.
 		}
-		if len(x.bodyb) == 0 {
-			return true, nil
+	}
+	if x.bodyb == nil {
+		dat, err := io.ReadAll(x.req.Body)
+		if err != nil {
+			return err
 		}
-		return true, json.Unmarshal(x.bodyb, target)
+		x.bodyb = dat
+	}
+	if len(x.bodyb) == 0 {
+		return nil
+	}
+	dec, ok := lookupDecoder(mt)
+	if !ok {
+		return ErrUnsupportedMediaType
 	}
-	return false, nil
+	return dec(x.bodyb, target)
 }
 
-func (x *valueExtractor) newValueByType(typ reflect.Type) (reflect.Value, error) {
+// newValueByType allocates a new value of typ and fills it from the request.
+// plan may be nil, in which case it is looked up from the cache.
+func (x *valueExtractor) newValueByType(typ reflect.Type, plan *paramsPlan) (reflect.Value, error) {
 	arg := reflect.New(typ)
 	switch typ.Kind() {
 	case reflect.Struct:
-		argVal := arg.Interface()
-		if err := x.unmarshalPathAndForm(argVal); err != nil {
-			return arg, fmt.Errorf("%v", err)
+		if err := x.unmarshalPathAndForm(arg.Elem(), plan); err != nil {
+			return arg, err
 		}
-		if isJson, err := x.unmarshalJSON(argVal); isJson && err != nil {
-			return arg, fmt.Errorf("%v", err)
+		if err := x.decodeBody(arg.Interface()); err != nil {
+			return arg, err
 		}
-	case reflect.String:
-		argVal := ""
-		if _, err := x.unmarshalJSON(&argVal); err != nil {
-			return arg, fmt.Errorf("%v", err)
+	case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+	default:
+		if err := x.decodeBody(arg.Interface()); err != nil {
+			return arg, err
 		}
-		arg.Elem().SetString(argVal)
 	}
 	return arg, nil
 }
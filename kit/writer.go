@@ -6,12 +6,27 @@ import (
 	"net"
 	"net/http"
 	"sync/atomic"
+	"time"
 )
 
+// ResponseStats describes a response written through a bound func.
+type ResponseStats struct {
+	Status   int           // 0 if nothing was written.
+	Bytes    int64         // bytes of the body written.
+	TTFB     time.Duration // time to the first write, 0 if nothing was written.
+	Duration time.Duration // time since the request was received.
+	Hijacked bool
+}
+
 type monitoredWriter struct {
 	base        http.ResponseWriter
 	headerWrote *atomic.Bool
 	hijacked    *atomic.Bool
+
+	start     time.Time
+	status    atomic.Int64
+	bytes     atomic.Int64
+	firstByte atomic.Int64 // nanoseconds since start, 0 until the first write.
 }
 
 var _ http.ResponseWriter = (*monitoredWriter)(nil)
@@ -23,9 +38,32 @@ func newMonitoredWriter(base http.ResponseWriter) *monitoredWriter {
 		base:        base,
 		headerWrote: new(atomic.Bool),
 		hijacked:    new(atomic.Bool),
+		start:       time.Now(),
 	}
 }
 
+// Stats returns the stats of the response so far.
+func (w *monitoredWriter) Stats() ResponseStats {
+	return ResponseStats{
+		Status:   int(w.status.Load()),
+		Bytes:    w.bytes.Load(),
+		TTFB:     time.Duration(w.firstByte.Load()),
+		Duration: time.Since(w.start),
+		Hijacked: w.hijacked.Load(),
+	}
+}
+
+func (w *monitoredWriter) started(code int) {
+	if w.status.CompareAndSwap(0, int64(code)) {
+		w.firstByte.CompareAndSwap(0, int64(max(time.Since(w.start), 1)))
+	}
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController.
+func (w *monitoredWriter) Unwrap() http.ResponseWriter {
+	return w.base
+}
+
 func (w *monitoredWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if hj, ok := w.base.(http.Hijacker); ok {
 		if w.hijacked.CompareAndSwap(false, true) {
@@ -52,14 +90,22 @@ func (w *monitoredWriter) Header() http.Header {
 
 func (w *monitoredWriter) Write(dat []byte) (int, error) {
 	w.headerWrote.CompareAndSwap(false, true)
-	return w.base.Write(dat)
+	w.started(http.StatusOK)
+	n, err := w.base.Write(dat)
+	w.bytes.Add(int64(n))
+	return n, err
 }
 
 func (w *monitoredWriter) WriteHeader(code int) {
 	if w.hijacked.Load() {
 		return
 	}
+	if w.headerWrote.Load() {
+		return
+	}
+	// marked only once written: WriteHeader panics on an invalid code.
+	w.base.WriteHeader(code)
 	if w.headerWrote.CompareAndSwap(false, true) {
-		w.base.WriteHeader(code)
+		w.started(code)
 	}
 }
@@ -0,0 +1,287 @@
+// Package metrics provides counters, gauges and histograms rendered in the
+// Prometheus text exposition format, without dependencies.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefBuckets are the default buckets of histograms, in seconds.
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var namePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// atomicFloat is a float64 updated atomically.
+type atomicFloat struct {
+	bits atomic.Uint64
+}
+
+func (f *atomicFloat) Add(v float64) {
+	for {
+		old := f.bits.Load()
+		if f.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+func (f *atomicFloat) Set(v float64) {
+	f.bits.Store(math.Float64bits(v))
+}
+
+func (f *atomicFloat) Load() float64 {
+	return math.Float64frombits(f.bits.Load())
+}
+
+// Counter is a value which only goes up.
+type Counter struct {
+	v atomicFloat
+}
+
+// Inc adds 1.
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+// Add adds v, which must not be negative.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease.")
+	}
+	c.v.Add(v)
+}
+
+// Value returns the current value.
+func (c *Counter) Value() float64 {
+	return c.v.Load()
+}
+
+// Gauge is a value which goes up and down.
+type Gauge struct {
+	v atomicFloat
+}
+
+// Set sets the value to v.
+func (g *Gauge) Set(v float64) {
+	g.v.Set(v)
+}
+
+// Add adds v, which may be negative.
+func (g *Gauge) Add(v float64) {
+	g.v.Add(v)
+}
+
+// Inc adds 1.
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+// Dec subtracts 1.
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+// Value returns the current value.
+func (g *Gauge) Value() float64 {
+	return g.v.Load()
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	upper  []float64 // sorted upper bounds, without +Inf.
+	counts []atomic.Uint64
+	count  atomic.Uint64
+	sum    atomicFloat
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{upper: buckets, counts: make([]atomic.Uint64, len(buckets))}
+}
+
+// Observe records v.
+func (h *Histogram) Observe(v float64) {
+	if i := sort.SearchFloat64s(h.upper, v); i < len(h.upper) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+	h.sum.Add(v)
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count.Load()
+}
+
+// Sum returns the sum of observations.
+func (h *Histogram) Sum() float64 {
+	return h.sum.Load()
+}
+
+type kind string
+
+const (
+	kindCounter   kind = "counter"
+	kindGauge     kind = "gauge"
+	kindHistogram kind = "histogram"
+)
+
+// family is a metric with its children by label values.
+type family struct {
+	name    string
+	help    string
+	kind    kind
+	labels  []string
+	buckets []float64
+
+	mu       sync.RWMutex
+	children map[string]*child
+}
+
+type child struct {
+	values []string
+	metric interface{} // *Counter, *Gauge or *Histogram.
+}
+
+func (f *family) with(values []string) interface{} {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values. got %d.", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.RLock()
+	c, ok := f.children[key]
+	f.mu.RUnlock()
+	if ok {
+		return c.metric
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if c, ok := f.children[key]; ok {
+		return c.metric
+	}
+	c = &child{values: append([]string(nil), values...)}
+	switch f.kind {
+	case kindCounter:
+		c.metric = &Counter{}
+	case kindGauge:
+		c.metric = &Gauge{}
+	default:
+		c.metric = newHistogram(f.buckets)
+	}
+	f.children[key] = c
+	return c.metric
+}
+
+// CounterVec is a Counter partitioned by label values.
+type CounterVec struct{ f *family }
+
+// With returns the Counter of the label values, in the order of the
+// label names.
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.f.with(values).(*Counter)
+}
+
+// GaugeVec is a Gauge partitioned by label values.
+type GaugeVec struct{ f *family }
+
+// With returns the Gauge of the label values.
+func (v *GaugeVec) With(values ...string) *Gauge {
+	return v.f.with(values).(*Gauge)
+}
+
+// HistogramVec is a Histogram partitioned by label values.
+type HistogramVec struct{ f *family }
+
+// With returns the Histogram of the label values.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.f.with(values).(*Histogram)
+}
+
+// Registry holds metrics by name.
+type Registry struct {
+	mu       sync.RWMutex
+	families map[string]*family
+}
+
+// Default is the Registry of the metrics of kit and ws.
+var Default = NewRegistry()
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{families: map[string]*family{}}
+}
+
+// register adds a family. It panics if the name or the labels are
+// invalid, or if the name is already registered.
+func (r *Registry) register(name, help string, k kind, buckets []float64, labels []string) *family {
+	if !namePattern.MatchString(name) {
+		panic(fmt.Sprintf("metrics: invalid metric name %q.", name))
+	}
+	for _, l := range labels {
+		if !namePattern.MatchString(l) || strings.Contains(l, ":") || strings.HasPrefix(l, "__") || (k == kindHistogram && l == "le") {
+			panic(fmt.Sprintf("metrics: invalid label name %q of %s.", l, name))
+		}
+	}
+	if k == kindHistogram {
+		if len(buckets) == 0 {
+			buckets = DefBuckets
+		}
+		buckets = append([]float64(nil), buckets...)
+		sort.Float64s(buckets)
+		if math.IsInf(buckets[len(buckets)-1], 1) {
+			buckets = buckets[:len(buckets)-1]
+		}
+	}
+	f := &family{
+		name:     name,
+		help:     help,
+		kind:     k,
+		labels:   append([]string(nil), labels...),
+		buckets:  buckets,
+		children: map[string]*child{},
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.families[name]; ok {
+		panic(fmt.Sprintf("metrics: %s is already registered.", name))
+	}
+	r.families[name] = f
+	return f
+}
+
+// NewCounter registers a Counter.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.register(name, help, kindCounter, nil, nil).with(nil).(*Counter)
+}
+
+// NewCounterVec registers a CounterVec with the label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{r.register(name, help, kindCounter, nil, labels)}
+}
+
+// NewGauge registers a Gauge.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return r.register(name, help, kindGauge, nil, nil).with(nil).(*Gauge)
+}
+
+// NewGaugeVec registers a GaugeVec with the label names.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{r.register(name, help, kindGauge, nil, labels)}
+}
+
+// NewHistogram registers a Histogram with the bucket upper bounds, or
+// DefBuckets if buckets is empty.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.register(name, help, kindHistogram, buckets, nil).with(nil).(*Histogram)
+}
+
+// NewHistogramVec registers a HistogramVec with the bucket upper bounds
+// and the label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{r.register(name, help, kindHistogram, buckets, labels)}
+}
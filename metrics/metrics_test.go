@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("jobs_total", "Jobs done.\nBy queue.", "queue")
+	c.With(`a"b`).Add(2)
+	c.With("default").Inc()
+	g := r.NewGauge("workers", "")
+	g.Set(3)
+	g.Dec()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{1, 0.1})
+	for _, v := range []float64{0.05, 0.1, 0.5, 3} {
+		h.Observe(v)
+	}
+	r.NewCounterVec("unused_total", "Never used.", "x")
+
+	expects := strings.Join([]string{
+		"# HELP jobs_total Jobs done.\\nBy queue.",
+		"# TYPE jobs_total counter",
+		`jobs_total{queue="a\"b"} 2`,
+		`jobs_total{queue="default"} 1`,
+		"# HELP latency_seconds Latency.",
+		"# TYPE latency_seconds histogram",
+		`latency_seconds_bucket{le="0.1"} 2`,
+		`latency_seconds_bucket{le="1"} 3`,
+		`latency_seconds_bucket{le="+Inf"} 4`,
+		"latency_seconds_sum 3.65",
+		"latency_seconds_count 4",
+		"# TYPE workers gauge",
+		"workers 2",
+		"",
+	}, "\n")
+
+	w := httptest.NewRecorder()
+	Handler(r).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Body.String() != expects {
+		t.Fatalf("text is not expected:\n%s", w.Body.String())
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("content type is not expected: %s", ct)
+		return
+	}
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("a_total", "")
+	for i, fn := range []func(){
+		func() { r.NewCounter("a_total", "") },
+		func() { r.NewGauge("1abc", "") },
+		func() { r.NewHistogramVec("h", "", nil, "le") },
+		func() { r.NewCounterVec("b_total", "", "x").With("1", "2") },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("case %d: no panic.", i)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the media type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Handler returns a handler writing the metrics of r, or of Default if r
+// is nil, in the Prometheus text exposition format.
+func Handler(r *Registry) http.Handler {
+	if r == nil {
+		r = Default
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		r.WriteText(w)
+	})
+}
+
+// WriteText writes the metrics of r in the Prometheus text exposition
+// format, sorted by name and label values.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.RUnlock()
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+func (f *family) writeText(w *bufio.Writer) {
+	f.mu.RLock()
+	children := make([]*child, 0, len(f.children))
+	for _, c := range f.children {
+		children = append(children, c)
+	}
+	f.mu.RUnlock()
+	if len(children) == 0 {
+		return
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return strings.Join(children[i].values, "\xff") < strings.Join(children[j].values, "\xff")
+	})
+
+	if len(f.help) > 0 {
+		w.WriteString("# HELP " + f.name + " " + escapeHelp(f.help) + "\n")
+	}
+	w.WriteString("# TYPE " + f.name + " " + string(f.kind) + "\n")
+	for _, c := range children {
+		labels := f.labelPairs(c.values)
+		switch m := c.metric.(type) {
+		case *Counter:
+			writeSample(w, f.name, labels, "", m.Value())
+		case *Gauge:
+			writeSample(w, f.name, labels, "", m.Value())
+		case *Histogram:
+			cumulative := uint64(0)
+			for i, upper := range m.upper {
+				cumulative += m.counts[i].Load()
+				writeSample(w, f.name+"_bucket", labels, formatFloat(upper), float64(cumulative))
+			}
+			count := m.count.Load()
+			writeSample(w, f.name+"_bucket", labels, "+Inf", float64(count))
+			writeSample(w, f.name+"_sum", labels, "", m.sum.Load())
+			writeSample(w, f.name+"_count", labels, "", float64(count))
+		}
+	}
+}
+
+func (f *family) labelPairs(values []string) string {
+	pairs := make([]string, len(values))
+	for i, v := range values {
+		pairs[i] = f.labels[i] + `="` + escapeLabel(v) + `"`
+	}
+	return strings.Join(pairs, ",")
+}
+
+func writeSample(w *bufio.Writer, name, labels, le string, v float64) {
+	w.WriteString(name)
+	if len(le) > 0 {
+		if len(labels) > 0 {
+			labels += ","
+		}
+		labels += `le="` + le + `"`
+	}
+	if len(labels) > 0 {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,184 @@
+// Package trace propagates W3C trace-context (traceparent and tracestate
+// headers) and starts spans through a vendor-neutral Tracer hook.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const (
+	TraceparentHeader = "Traceparent"
+	TracestateHeader  = "Tracestate"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// IsValid reports whether id is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether id is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// FlagSampled is the sampled bit of the trace flags.
+const FlagSampled byte = 0x01
+
+// SpanContext is the part of a span propagated across processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	State   string // the tracestate header, passed on as is.
+}
+
+// IsValid reports whether sc has both a trace and a span id.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Sampled reports whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// Traceparent formats sc as a version 00 traceparent header.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", sc.TraceID, sc.SpanID, sc.Flags)
+}
+
+// ParseTraceparent parses a traceparent header. Headers of future
+// versions are read as version 00, as the specification requires.
+func ParseTraceparent(s string) (SpanContext, error) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent: %q.", s)
+	}
+	version, err := hexByte(parts[0])
+	if err != nil || version == 0xff || (version == 0 && len(parts) != 4) {
+		return sc, fmt.Errorf("invalid traceparent version: %q.", s)
+	}
+	if !isLowerHex(parts[1]) || !isLowerHex(parts[2]) {
+		return sc, fmt.Errorf("invalid traceparent: %q.", s)
+	}
+	hex.Decode(sc.TraceID[:], []byte(parts[1]))
+	hex.Decode(sc.SpanID[:], []byte(parts[2]))
+	if sc.Flags, err = hexByte(parts[3]); err != nil {
+		return sc, fmt.Errorf("invalid traceparent flags: %q.", s)
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent: zero id in %q.", s)
+	}
+	return sc, nil
+}
+
+func hexByte(s string) (byte, error) {
+	if !isLowerHex(s) {
+		return 0, fmt.Errorf("not lower case hex: %q.", s)
+	}
+	b := [1]byte{}
+	_, err := hex.Decode(b[:], []byte(s))
+	return b[0], err
+}
+
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Extract reads the span context of the traceparent and tracestate
+// headers of h.
+func Extract(h http.Header) (SpanContext, bool) {
+	sc, err := ParseTraceparent(h.Get(TraceparentHeader))
+	if err != nil {
+		return sc, false
+	}
+	sc.State = strings.Join(h.Values(TracestateHeader), ",")
+	return sc, true
+}
+
+// Inject sets the traceparent and tracestate headers of h from the span
+// context of ctx, if any.
+func Inject(ctx context.Context, h http.Header) {
+	sc := SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	h.Set(TraceparentHeader, sc.Traceparent())
+	if len(sc.State) > 0 {
+		h.Set(TracestateHeader, sc.State)
+	} else {
+		h.Del(TracestateHeader)
+	}
+}
+
+type spanKey struct{}
+
+type remoteKey struct{}
+
+// ContextWithSpan returns ctx carrying span.
+func ContextWithSpan(ctx context.Context, span Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span of ctx, or nil.
+func SpanFromContext(ctx context.Context) Span {
+	span, _ := ctx.Value(spanKey{}).(Span)
+	return span
+}
+
+// ContextWithRemote returns ctx carrying sc, received from another
+// process, as the parent of spans started from it.
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context of the span of ctx, or
+// the remote span context of ctx, or a zero SpanContext.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	sc, _ := ctx.Value(remoteKey{}).(SpanContext)
+	return sc
+}
+
+func newTraceID() TraceID {
+	id := TraceID{}
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	id := SpanID{}
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return id
+}
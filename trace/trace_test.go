@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	valid := []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-future",
+	}
+	for i, s := range valid {
+		sc, err := ParseTraceparent(s)
+		if err != nil {
+			t.Fatalf("case %d: %v", i, err)
+			return
+		}
+		if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+			t.Fatalf("case %d: ids are not expected: %+v", i, sc)
+			return
+		}
+	}
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0x",
+	}
+	for i, s := range invalid {
+		if _, err := ParseTraceparent(s); err == nil {
+			t.Fatalf("case %d: %q is accepted.", i, s)
+			return
+		}
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	in := http.Header{}
+	in.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	in.Set(TracestateHeader, "vendor=x")
+	sc, ok := Extract(in)
+	if !ok || !sc.Sampled() || sc.State != "vendor=x" {
+		t.Fatalf("unexpected span context: %+v", sc)
+		return
+	}
+
+	spans := []*SpanData{}
+	tracer := NewTracer(func(d *SpanData) { spans = append(spans, d) })
+	ctx, root := tracer.Start(ContextWithRemote(context.Background(), sc), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+	root.End()
+
+	if len(spans) != 2 || spans[0].Name != "child" || spans[1].Name != "root" {
+		t.Fatalf("unexpected spans: %+v", spans)
+		return
+	}
+	if spans[1].Parent != sc.SpanID || !spans[1].Remote || spans[1].Context.TraceID != sc.TraceID {
+		t.Fatalf("root is not a child of the remote span: %+v", spans[1])
+		return
+	}
+	if spans[0].Parent != spans[1].Context.SpanID || spans[0].Remote {
+		t.Fatalf("child is not a child of root: %+v", spans[0])
+		return
+	}
+
+	out := http.Header{}
+	Inject(ctx, out)
+	if out.Get(TraceparentHeader) != root.SpanContext().Traceparent() || out.Get(TracestateHeader) != "vendor=x" {
+		t.Fatalf("unexpected headers: %v", out)
+		return
+	}
+}
+
+func TestStartWithoutTracer(t *testing.T) {
+	sc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, span := Start(ContextWithRemote(context.Background(), sc), "x")
+	span.End()
+	if span.SpanContext() != sc || SpanContextFromContext(ctx) != sc {
+		t.Fatalf("span context is not passed on.")
+		return
+	}
+}
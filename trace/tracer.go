@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Span is a timed operation of a trace.
+type Span interface {
+	SpanContext() SpanContext
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts spans. The parent of a span is the span of ctx, or its
+// remote span context; see ContextWithRemote. The returned context carries
+// the new span.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+type tracerHolder struct {
+	t Tracer
+}
+
+var global atomic.Pointer[tracerHolder]
+
+// SetTracer sets the Tracer of Start. A nil Tracer disables spans.
+func SetTracer(t Tracer) {
+	global.Store(&tracerHolder{t: t})
+}
+
+// GetTracer returns the Tracer set by SetTracer, or nil.
+func GetTracer() Tracer {
+	if h := global.Load(); h != nil {
+		return h.t
+	}
+	return nil
+}
+
+// Start starts a span with the Tracer set by SetTracer. Without one, it
+// returns ctx and a span doing nothing, which carries the span context
+// of ctx so that it is still propagated.
+func Start(ctx context.Context, name string) (context.Context, Span) {
+	if t := GetTracer(); t != nil {
+		return t.Start(ctx, name)
+	}
+	return ctx, noopSpan{sc: SpanContextFromContext(ctx)}
+}
+
+type noopSpan struct {
+	sc SpanContext
+}
+
+func (s noopSpan) SpanContext() SpanContext               { return s.sc }
+func (s noopSpan) SetAttribute(key string, v interface{}) {}
+func (s noopSpan) RecordError(err error)                  {}
+func (s noopSpan) End()                                   {}
+
+// SpanData is a span ended by a Tracer of NewTracer.
+type SpanData struct {
+	Name       string
+	Context    SpanContext
+	Parent     SpanID // zero for a root span.
+	Remote     bool   // the parent is from another process.
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]interface{}
+	Errors     []error
+}
+
+// NewTracer returns a Tracer generating ids, which passes every ended
+// span to export. Spans of a new trace are sampled; others follow the
+// sampled flag of their parent.
+func NewTracer(export func(*SpanData)) Tracer {
+	return &basicTracer{export: export}
+}
+
+type basicTracer struct {
+	export func(*SpanData)
+}
+
+func (t *basicTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent := SpanContextFromContext(ctx)
+	d := &SpanData{Name: name, Start: time.Now()}
+	if parent.IsValid() {
+		d.Context = SpanContext{TraceID: parent.TraceID, Flags: parent.Flags, State: parent.State}
+		d.Parent = parent.SpanID
+		d.Remote = SpanFromContext(ctx) == nil
+	} else {
+		d.Context = SpanContext{TraceID: newTraceID(), Flags: FlagSampled}
+	}
+	d.Context.SpanID = newSpanID()
+	span := &basicSpan{t: t, d: d}
+	return ContextWithSpan(ctx, span), span
+}
+
+type basicSpan struct {
+	t     *basicTracer
+	mu    sync.Mutex
+	d     *SpanData
+	ended bool
+}
+
+func (s *basicSpan) SpanContext() SpanContext {
+	return s.d.Context
+}
+
+func (s *basicSpan) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.d.Attributes == nil {
+		s.d.Attributes = map[string]interface{}{}
+	}
+	s.d.Attributes[key] = value
+}
+
+func (s *basicSpan) RecordError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.d.Errors = append(s.d.Errors, err)
+}
+
+func (s *basicSpan) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.d.End = time.Now()
+	s.mu.Unlock()
+	if s.t.export != nil && s.d.Context.Sampled() {
+		s.t.export(s.d)
+	}
+}
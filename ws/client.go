@@ -3,6 +3,7 @@ package ws
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha1"
 	"crypto/tls"
@@ -14,7 +15,8 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
-	"sync"
+
+	"github.com/smallfz/httpkit/trace"
 )
 
 var UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36"
@@ -22,6 +24,10 @@ var UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537
 type DialOptions struct {
 	Header http.Header
 	TLS    *tls.Config
+
+	// Context carries the span whose traceparent is sent with the
+	// handshake, unless Header already has one. See package trace.
+	Context context.Context
 }
 
 func Dial(uriStr string) (WSConn, error) {
@@ -66,7 +72,7 @@ func DialWithOptions(uriStr string, options *DialOptions) (WSConn, error) {
 	}
 
 	// connect to server
-	hostAddr := fmt.Sprintf("%s:%d", host, port)
+	hostAddr := net.JoinHostPort(host, strconv.Itoa(port))
 	conn := (net.Conn)(nil)
 	if useTLS {
 		cfg := &tls.Config{
@@ -108,11 +114,20 @@ func DialWithOptions(uriStr string, options *DialOptions) (WSConn, error) {
 	fmt.Fprintf(tx, "Upgrade: websocket\r\n")
 	fmt.Fprintf(tx, "Sec-WebSocket-Version: 7\r\n")
 	fmt.Fprintf(tx, "Sec-WebSocket-Key: %s\r\n", hsKey)
-	if options != nil && len(options.Header) > 0 {
-		for name, _ := range options.Header {
-			value := options.Header.Get(name)
-			fmt.Fprintf(tx, "%s: %s\r\n", name, value)
+	header := http.Header{}
+	sc := trace.SpanContext{}
+	if options != nil {
+		for name, values := range options.Header {
+			header[name] = values
 		}
+		if options.Context != nil && len(header.Get(trace.TraceparentHeader)) == 0 {
+			trace.Inject(options.Context, header)
+		}
+		sc, _ = trace.Extract(header)
+	}
+	for name, _ := range header {
+		value := header.Get(name)
+		fmt.Fprintf(tx, "%s: %s\r\n", name, value)
 	}
 	fmt.Fprintf(tx, "\r\n")
 	tx.Flush()
@@ -155,7 +170,7 @@ func DialWithOptions(uriStr string, options *DialOptions) (WSConn, error) {
 		return nil, errHsFail
 	}
 
-	lckR := new(sync.Mutex)
-	lckW := new(sync.Mutex)
-	return &wsConn{conn: conn, tx: tx, lckR: lckR, lckW: lckW, mask: true}, nil
+	wsc := newWSConn(conn, tx, true)
+	wsc.trace = sc
+	return wsc, nil
 }
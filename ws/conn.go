@@ -8,6 +8,8 @@ import (
 	"io"
 	"net"
 	"sync"
+
+	"github.com/smallfz/httpkit/trace"
 )
 
 type WSFrame struct {
@@ -100,10 +102,39 @@ type wsConn struct {
 	lckW *sync.Mutex
 
 	mask bool // true for websocket client, false for server.
+
+	closeOnce *sync.Once
+	metrics   *wsMetrics
+
+	trace trace.SpanContext // of the handshake, if any.
 }
 
 var _ WSConn = (*wsConn)(nil)
 
+func newWSConn(conn net.Conn, tx *bufio.ReadWriter, mask bool) *wsConn {
+	f := &wsConn{
+		conn:      conn,
+		tx:        tx,
+		lckR:      new(sync.Mutex),
+		lckW:      new(sync.Mutex),
+		mask:      mask,
+		closeOnce: new(sync.Once),
+		metrics:   current.Load(),
+	}
+	f.metrics.connsActive.With(f.side()).Inc()
+	return f
+}
+
+// TraceContext returns the span context of the handshake of conn: the one
+// sent by DialWithOptions, or the one received by WebSocketHandshake. It
+// is zero if there was none.
+func TraceContext(conn WSConn) trace.SpanContext {
+	if c, ok := conn.(*wsConn); ok {
+		return c.trace
+	}
+	return trace.SpanContext{}
+}
+
 func (t *wsConn) RemoteAddr() net.Addr {
 	return t.conn.RemoteAddr()
 }
@@ -120,6 +151,7 @@ func (f *wsConn) ReadFrame() (*WSFrame, error) {
 	fin := header[0]&(1<<7) > 0
 	opCode := header[0] & 0b1111
 	if opCode == 8 {
+		f.countFrame("in", opCode)
 		return &WSFrame{Fin: fin, Op: opCode}, io.EOF
 	}
 
@@ -164,6 +196,8 @@ func (f *wsConn) ReadFrame() (*WSFrame, error) {
 		}
 	}
 
+	f.countFrame("in", opCode)
+
 	// slog.Debug(
 	// 	"received frame:",
 	// 	"fin", fin,
@@ -252,6 +286,7 @@ func (f *wsConn) writeFrame(m *WSFrame) (int, error) {
 
 	if size, err := f.tx.Write(chunkFin); err == nil {
 		f.tx.Flush()
+		f.countFrame("out", m.Op)
 		return size, nil
 	} else {
 		return size, err
@@ -259,6 +294,9 @@ func (f *wsConn) writeFrame(m *WSFrame) (int, error) {
 }
 
 func (f *wsConn) Close() error {
+	f.closeOnce.Do(func() {
+		f.metrics.connsActive.With(f.side()).Dec()
+	})
 	if f.conn != nil {
 		f.conn.Close()
 	}
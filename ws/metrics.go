@@ -0,0 +1,77 @@
+package ws
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/smallfz/httpkit/metrics"
+)
+
+// wsMetrics are the metrics of connections registered on a registry.
+type wsMetrics struct {
+	connsActive *metrics.GaugeVec
+	framesTotal *metrics.CounterVec
+}
+
+var (
+	metricsMu  sync.Mutex
+	metricsFor = map[*metrics.Registry]*wsMetrics{}
+	current    atomic.Pointer[wsMetrics]
+)
+
+func wsMetricsOf(reg *metrics.Registry) *wsMetrics {
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	if m, ok := metricsFor[reg]; ok {
+		return m
+	}
+	m := &wsMetrics{
+		connsActive: reg.NewGaugeVec("ws_connections_active",
+			"Open WebSocket connections, by side: server or client.", "side"),
+		framesTotal: reg.NewCounterVec("ws_frames_total",
+			"WebSocket frames, by side, direction (in or out) and opcode.", "side", "direction", "op"),
+	}
+	metricsFor[reg] = m
+	return m
+}
+
+func init() {
+	current.Store(wsMetricsOf(metrics.Default))
+}
+
+// SetMetrics makes connections opened from now on record their metrics
+// on reg, or on metrics.Default if reg is nil.
+func SetMetrics(reg *metrics.Registry) {
+	if reg == nil {
+		reg = metrics.Default
+	}
+	current.Store(wsMetricsOf(reg))
+}
+
+var opNames = map[uint8]string{
+	0:  "continuation",
+	1:  "text",
+	2:  "binary",
+	8:  "close",
+	9:  "ping",
+	10: "pong",
+}
+
+func opName(op uint8) string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return strconv.Itoa(int(op))
+}
+
+func (f *wsConn) side() string {
+	if f.mask {
+		return "client"
+	}
+	return "server"
+}
+
+func (f *wsConn) countFrame(direction string, op uint8) {
+	f.metrics.framesTotal.With(f.side(), direction, opName(op)).Inc()
+}
@@ -6,7 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
-	"sync"
+
+	"github.com/smallfz/httpkit/trace"
 )
 
 func WebSocketHandshake(req *http.Request, w http.ResponseWriter) (WSConn, error) {
@@ -54,7 +55,10 @@ func WebSocketHandshake(req *http.Request, w http.ResponseWriter) (WSConn, error
 	fmt.Fprintf(tx, "\r\n")
 	tx.Flush()
 
-	lckR := new(sync.Mutex)
-	lckW := new(sync.Mutex)
-	return &wsConn{conn: conn, tx: tx, lckR: lckR, lckW: lckW}, nil
+	wsc := newWSConn(conn, tx, false)
+	wsc.trace = trace.SpanContextFromContext(req.Context())
+	if !wsc.trace.IsValid() {
+		wsc.trace, _ = trace.Extract(header)
+	}
+	return wsc, nil
 }